@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/*
+Binary payloads can be represented as raw bytes, hex, base64 or base64url. This is used by the encode and decode
+subcommands as well as for the http bodies via --request-encoding and --response-encoding - which allows
+talking to gateways wrapping the Protobuf payloads in base64, e.g. with the Content-Type application/x-protobuf;base64.
+
+The request body is encoded after the request was encoded into the binary format and
+the response body is decoded before it is decoded against the response type.
+*/
+
+const (
+	BinaryRaw       = "raw"
+	BinaryHex       = "hex"
+	BinaryBase64    = "base64"
+	BinaryBase64Url = "base64url"
+)
+
+var binaryFormats = []string{BinaryRaw, BinaryHex, BinaryBase64, BinaryBase64Url}
+
+func ensureBinaryFormatIsKnown(format string, flagName string) {
+	if !slices.Contains(binaryFormats, format) {
+		PanicWithMessage(fmt.Sprintf("Unknown binary format %s. Expected %s, %s, %s or %s for %s", format, BinaryRaw, BinaryHex, BinaryBase64, BinaryBase64Url, flagName))
+	}
+}
+
+func encodeBinaryAs(binary []byte, format string) []byte {
+	switch format {
+	case BinaryHex:
+		return []byte(hex.EncodeToString(binary))
+	case BinaryBase64:
+		return []byte(base64.StdEncoding.EncodeToString(binary))
+	case BinaryBase64Url:
+		return []byte(base64.URLEncoding.EncodeToString(binary))
+	default:
+		return binary
+	}
+}
+
+// Whitespace is irrelevant in all formats except raw and allows for line breaks and grouped bytes.
+// A JSON string envelope around the encoded payload is removed. The padding of base64url is optional.
+func decodeBinaryAs(input []byte, format string) ([]byte, error) {
+	if format == BinaryRaw {
+		return input, nil
+	}
+
+	text := strings.TrimSpace(string(input))
+	if strings.HasPrefix(text, "\"") {
+		if err := json.Unmarshal([]byte(text), &text); err != nil {
+			return nil, err
+		}
+	}
+	withoutWhitespace := strings.Join(strings.Fields(text), "")
+
+	switch format {
+	case BinaryHex:
+		return hex.DecodeString(withoutWhitespace)
+	case BinaryBase64:
+		return base64.StdEncoding.DecodeString(withoutWhitespace)
+	default:
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(withoutWhitespace, "="))
+	}
+}
+
+func encodeRequestBody(requestBinary []byte) []byte {
+	if CurrentConfig.RequestEncoding == BinaryRaw {
+		return requestBinary
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Encoding the request body as %s.\n", CurrentConfig.RequestEncoding)
+	}
+	return encodeBinaryAs(requestBinary, CurrentConfig.RequestEncoding)
+}
+
+// The bodies of unaccepted responses are kept as they are, if they cannot be decoded. They are usually plain error messages.
+func decodeResponseBody(responseBody []byte, responseHeaders string) []byte {
+	if CurrentConfig.ResponseEncoding == BinaryRaw {
+		return responseBody
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Decoding the response body as %s.\n", CurrentConfig.ResponseEncoding)
+	}
+	responseBinary, err := decodeBinaryAs(responseBody, CurrentConfig.ResponseEncoding)
+	if err != nil && !isStatusCodeAccepted(statusCodeOfHeaders(responseHeaders)) {
+		if CurrentConfig.Verbose {
+			fmt.Printf("Keeping the body of the unsuccessful response as it is, as it could not be decoded as %s.\n", CurrentConfig.ResponseEncoding)
+		}
+		return responseBody
+	}
+	PanicWithMessageOnError(err, func() string { return "Could not decode the response body as " + CurrentConfig.ResponseEncoding + "." })
+	return responseBinary
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+/*
+A collection is a JSON file with named requests. The run subcommand converts the .proto files once and
+runs the selected requests (or all of them) in the order of the file. Failing requests are reported and the
+remaining requests are run nonetheless.
+
+Each string in a request may reference variables via ${VAR}. These are resolved from the profile file
+(lines of VAR=value) and the environment variables. Variables from the profile take precedence.
+
+Example:
+	{
+	  "requests": [
+	    {
+	      "name": "happy-day",
+	      "url": "${BASE_URL}/happy-day/verify",
+	      "requestType": "..HappyDayRequest",
+	      "responseType": "..HappyDayResponse",
+	      "headers": ["Authorization: Bearer ${TOKEN}"],
+	      "data": "includeReason: true"
+	    }
+	  ]
+	}
+*/
+
+type RequestCollection struct {
+	Requests []CollectionRequest `json:"requests"`
+}
+
+type CollectionRequest struct {
+	Name         string   `json:"name"`
+	Url          string   `json:"url"`
+	Method       string   `json:"method"`
+	Headers      []string `json:"headers"`
+	RequestType  string   `json:"requestType"`
+	ResponseType string   `json:"responseType"`
+	ErrorType    string   `json:"errorType"`
+	GrpcMethod   string   `json:"grpc"`
+	RpcMethod    string   `json:"rpc"`
+	In           string   `json:"in"`
+	Out          string   `json:"out"`
+	Data         string   `json:"data"`
+
+	ExpectedResponse   string   `json:"expect"`
+	ExpectPartialMatch bool     `json:"expectPartial"`
+	ExpectedStatusCode int      `json:"expectStatus"`
+	ExpectedHeaders    []string `json:"expectHeaders"`
+}
+
+var collectionVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)}`)
+
+var runCollectionFile string
+var runProfileFile string
+
+var runCmd = &cobra.Command{
+	Short: "Runs the named requests of a collection file. All requests are run, if no names are given.",
+	Use: "run [flags] --collection file [request-name...]\n\n" +
+		"The .proto files are converted once and used for all requests of the collection.\n" +
+		"A collection is a JSON file of the form {\"requests\": [{\"name\": ..., \"url\": ..., \"method\": ..., \"headers\": [...], \"requestType\": ..., \"responseType\": ..., \"errorType\": ..., \"grpc\": ..., \"rpc\": ..., \"in\": ..., \"out\": ..., \"data\": ...,\n" +
+		"\"expect\": ..., \"expectPartial\": ..., \"expectStatus\": ..., \"expectHeaders\": [...]}]}. The expectations correspond to the --expect flags.\n" +
+		"Omitted values of a request are taken from the flags. The headers of a request are added to the headers given via -H.\n" +
+		"The values may reference variables via ${VAR}. They are resolved from the --profile file (lines of VAR=value) and the environment variables.\n" +
+		"Failing requests are reported and the remaining requests are run nonetheless. The exit code is non-zero, if any request failed.",
+	Example:               "  protocurl run -I my-protos --collection requests.json --profile staging.env happy-day",
+	Args:                  cobra.ArbitraryArgs,
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		requests := selectCollectionRequests(readCollection(runCollectionFile), args)
+		variables := readCollectionVariables(runProfileFile)
+		for i := range requests {
+			substituteCollectionVariables(&requests[i], variables)
+		}
+
+		registry := convertProtoFilesToProtoRegistryFilesWithRawFormat()
+
+		runCollectionRequests(requests, registry, cmd.Flags().Changed("method"))
+	},
+}
+
+func initialiseRunCommand() {
+	flags := runCmd.Flags()
+	addFlags(flags)
+
+	flags.StringVar(&runCollectionFile, "collection", "",
+		"Mandatory: The JSON `file` containing the named requests.")
+	AssertSuccess(runCmd.MarkFlagRequired("collection"))
+
+	flags.StringVar(&runProfileFile, "profile", "",
+		"The `file` with lines of VAR=value used to resolve ${VAR} in the collection. Takes precedence over environment variables.")
+
+	rootCmd.AddCommand(runCmd)
+}
+
+func readCollection(collectionFile string) RequestCollection {
+	file, err := os.Open(collectionFile)
+	PanicOnError(err)
+	defer func() { _ = file.Close() }()
+
+	var collection RequestCollection
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	PanicWithMessageOnError(decoder.Decode(&collection), func() string {
+		return "Could not read collection " + collectionFile + "."
+	})
+
+	alreadyDefined := make(map[string]bool)
+	for _, request := range collection.Requests {
+		if request.Name == "" {
+			PanicWithMessage("Every request in collection " + collectionFile + " needs a name.")
+		}
+		if alreadyDefined[request.Name] {
+			PanicWithMessage("The request name " + request.Name + " is used multiple times in collection " + collectionFile + ".")
+		}
+		alreadyDefined[request.Name] = true
+	}
+
+	return collection
+}
+
+func selectCollectionRequests(collection RequestCollection, names []string) []CollectionRequest {
+	if len(names) == 0 {
+		return collection.Requests
+	}
+
+	var selected []CollectionRequest
+	for _, name := range names {
+		found := false
+		for _, request := range collection.Requests {
+			if request.Name == name {
+				selected = append(selected, request)
+				found = true
+			}
+		}
+		if !found {
+			PanicWithMessage("No request named " + name + " found in collection " + runCollectionFile + ".")
+		}
+	}
+	return selected
+}
+
+func readCollectionVariables(profileFile string) map[string]string {
+	variables := make(map[string]string)
+	if profileFile == "" {
+		return variables
+	}
+
+	file, err := os.Open(profileFile)
+	PanicOnError(err)
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			PanicWithMessage(fmt.Sprintf("Expected VAR=value in line %d of profile %s. Got: %s", lineNumber, profileFile, line))
+		}
+		variables[strings.TrimSpace(name)] = value
+	}
+	PanicOnError(scanner.Err())
+
+	return variables
+}
+
+func substituteCollectionVariables(request *CollectionRequest, variables map[string]string) {
+	substitute := func(text string) string {
+		return collectionVariable.ReplaceAllStringFunc(text, func(reference string) string {
+			name := collectionVariable.FindStringSubmatch(reference)[1]
+			if value, found := variables[name]; found {
+				return value
+			}
+			if value, found := os.LookupEnv(name); found {
+				return value
+			}
+			PanicWithMessage("The variable " + name + " used in request " + request.Name + " is neither defined in the profile nor as an environment variable.")
+			return ""
+		})
+	}
+
+	for _, field := range []*string{&request.Url, &request.Method, &request.RequestType, &request.ResponseType,
+		&request.ErrorType, &request.GrpcMethod, &request.RpcMethod, &request.In, &request.Out, &request.Data, &request.ExpectedResponse} {
+		*field = substitute(*field)
+	}
+	for i := range request.Headers {
+		request.Headers[i] = substitute(request.Headers[i])
+	}
+	for i := range request.ExpectedHeaders {
+		request.ExpectedHeaders[i] = substitute(request.ExpectedHeaders[i])
+	}
+}
+
+func runCollectionRequests(requests []CollectionRequest, registry *protoregistry.Files, methodFlagProvided bool) {
+	baseConfig := CurrentConfig
+	baseInTextType := tmpInTextType
+	baseOutTextType := tmpOutTextType
+
+	var failedRequests []string
+	for _, request := range requests {
+		CurrentConfig = baseConfig
+		CurrentConfig.RequestHeaders = append([]string{}, baseConfig.RequestHeaders...)
+		tmpInTextType = baseInTextType
+		tmpOutTextType = baseOutTextType
+		applyCollectionRequest(request)
+		tmpMethodExplicitlyProvided = request.Method != "" || methodFlagProvided
+
+		if !CurrentConfig.SilentMode && !CurrentConfig.ShowOutputOnly {
+			fmt.Printf("%s Request %s %s\n", VISUAL_SEPARATOR, request.Name, VISUAL_SEPARATOR)
+		}
+
+		if !runCollectionRequest(registry) {
+			failedRequests = append(failedRequests, request.Name)
+		}
+	}
+
+	if len(failedRequests) != 0 {
+		PanicWithMessage(fmt.Sprintf("%d of %d requests failed: %s", len(failedRequests), len(requests), strings.Join(failedRequests, ", ")))
+	}
+}
+
+func applyCollectionRequest(request CollectionRequest) {
+	setIfNotEmpty := func(target *string, value string) {
+		if value != "" {
+			*target = value
+		}
+	}
+
+	setIfNotEmpty(&CurrentConfig.Url, request.Url)
+	setIfNotEmpty(&CurrentConfig.Method, request.Method)
+	setIfNotEmpty(&CurrentConfig.RequestType, request.RequestType)
+	setIfNotEmpty(&CurrentConfig.ResponseType, request.ResponseType)
+	setIfNotEmpty(&CurrentConfig.ErrorType, request.ErrorType)
+	setIfNotEmpty(&CurrentConfig.GrpcMethod, request.GrpcMethod)
+	setIfNotEmpty(&CurrentConfig.RpcMethod, request.RpcMethod)
+	setIfNotEmpty(&CurrentConfig.DataText, request.Data)
+	setIfNotEmpty(&tmpInTextType, request.In)
+	setIfNotEmpty(&tmpOutTextType, request.Out)
+	CurrentConfig.RequestHeaders = append(CurrentConfig.RequestHeaders, request.Headers...)
+
+	setIfNotEmpty(&CurrentConfig.ExpectedResponse, request.ExpectedResponse)
+	CurrentConfig.ExpectPartialMatch = CurrentConfig.ExpectPartialMatch || request.ExpectPartialMatch
+	if request.ExpectedStatusCode != 0 {
+		CurrentConfig.ExpectedStatusCode = request.ExpectedStatusCode
+	}
+	CurrentConfig.ExpectedHeaders = append(append([]string{}, CurrentConfig.ExpectedHeaders...), request.ExpectedHeaders...)
+}
+
+// Runs the request in CurrentConfig and reports whether it succeeded. Errors are printed.
+func runCollectionRequest(registry *protoregistry.Files) (success bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			PrintError(fmt.Errorf("%v", err))
+			success = false
+		}
+	}()
+
+	if CurrentConfig.Url == "" {
+		PanicWithMessage("No url was provided. Please provide it in the collection or via -u <url>.")
+	}
+
+	runSingleRequestWithRegistry(registry)
+	return true
+}
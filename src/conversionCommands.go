@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+/*
+The encode and decode subcommands convert between the text formats and the binary format without any
+http request. The input is read from the file given as argument or from stdin. The output is written
+to stdout or to the file given via --output-file.
+
+The binary payloads can be represented as raw bytes, hex, base64 or base64url. See bodyEncodings.go.
+*/
+
+var conversionMessageType string
+var conversionBinaryFormat string
+var conversionOutputFile string
+
+var encodeCmd = &cobra.Command{
+	Short: "Encodes a message from Protobuf text format or JSON into the binary format.",
+	Use: "encode [flags] -t message-type [input-file]\n\n" +
+		"Reads the message from the input file or stdin and writes the binary payload to stdout or --output-file.",
+	Example:               "  protocurl encode -I my-protos -t ..MyRequest --binary-format base64 request.txt",
+	Args:                  cobra.MaximumNArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		text := string(readConversionInput(args))
+		CurrentConfig.InTextType = conversionInTextType(text)
+
+		registry := convertProtoFilesToProtoRegistryFilesWithRawFormat()
+		var binary []byte
+		if CurrentConfig.Delimited {
+			binary = textToDelimitedBinary(conversionMessageType, text, registry)
+		} else {
+			binary, _ = textToMsgAndBinary(conversionMessageType, text, registry)
+		}
+
+		writeConversionOutput(formatBinary(binary))
+	},
+}
+
+var decodeCmd = &cobra.Command{
+	Short: "Decodes a binary payload into the Protobuf text format or JSON.",
+	Use: "decode [flags] [-t message-type] [input-file]\n\n" +
+		"Reads the binary payload from the input file or stdin and writes the message to stdout or --output-file.\n" +
+		"If no message type is provided, then the payload is decoded raw. See --decode-raw of protocurl.",
+	Example:               "  protocurl decode -I my-protos -t ..MyResponse --out json response.bin",
+	Args:                  cobra.MaximumNArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		binary := parseBinary(readConversionInput(args))
+		outTextType := conversionOutTextType()
+
+		messageType := conversionMessageType
+		if messageType == "" {
+			messageType = WellKnownEmptyMessageType
+		}
+
+		if CurrentConfig.SuggestTypes && CurrentConfig.Delimited {
+			PanicWithMessage("Message types can only be suggested for a single message. Please avoid --delimited.")
+		}
+
+		registry := convertProtoFilesToProtoRegistryFilesWithRawFormat()
+		var text string
+		if CurrentConfig.Delimited {
+			text = delimitedBinaryToText(messageType, binary, outTextType, registry)
+		} else {
+			text, _ = protoBinaryToMsgAndText(messageType, binary, outTextType, registry)
+		}
+
+		writeConversionOutput([]byte(text + "\n"))
+
+		if CurrentConfig.SuggestTypes {
+			displayMessageTypeSuggestions("Message Type Suggestions", binary, registry)
+		}
+	},
+}
+
+func initialiseConversionCommands() {
+	for _, command := range []*cobra.Command{encodeCmd, decodeCmd} {
+		flags := command.Flags()
+		addProtoFileFlags(flags)
+		addDelimitedFlag(flags)
+		addVerboseFlag(flags)
+
+		flags.StringVarP(&conversionMessageType, "type", "t", "",
+			"Message name or full package path of the Protobuf message type. The path can be shortened to '..', if the name of the message is unique.")
+
+		flags.StringVar(&conversionBinaryFormat, "binary-format", BinaryRaw,
+			"The representation of the binary payload. '"+BinaryRaw+"' uses the bytes as they are. '"+BinaryHex+"', '"+BinaryBase64+"' and '"+BinaryBase64Url+"' use the respective encodings.")
+
+		flags.StringVar(&conversionOutputFile, "output-file", "",
+			"Writes the output to the given `file` instead of stdout.")
+
+		rootCmd.AddCommand(command)
+	}
+
+	AssertSuccess(encodeCmd.MarkFlagRequired("type"))
+
+	encodeCmd.Flags().StringVar(&tmpInTextType, "in", "",
+		"Specifies, in which format the input should be interpreted in. 'text' uses the Protobuf text format and 'json' uses JSON. "+
+			"The type is inferred as JSON if the first token is a '{' and as text otherwise.")
+
+	decodeCmd.Flags().StringVar(&tmpOutTextType, "out", "",
+		"Produces the output in the specified format. 'text' (default) produces Protobuf text format. 'json' produces dense JSON and "+
+			"'json:pretty' produces pretty-printed JSON.")
+
+	decodeCmd.Flags().BoolVar(&CurrentConfig.SuggestTypes, "suggest-types", false,
+		"Scores all message types of the .proto files against the payload and shows the best matching ones after the output.")
+}
+
+func readConversionInput(args []string) []byte {
+	if len(args) == 0 || args[0] == "-" {
+		if CurrentConfig.Verbose {
+			fmt.Println("Reading input from stdin.")
+		}
+		input, err := io.ReadAll(os.Stdin)
+		PanicOnError(err)
+		return input
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Reading input from file %s.\n", args[0])
+	}
+	input, err := os.ReadFile(args[0])
+	PanicOnError(err)
+	return input
+}
+
+func writeConversionOutput(output []byte) {
+	if conversionOutputFile == "" {
+		_, err := os.Stdout.Write(output)
+		PanicOnError(err)
+		return
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Writing output to file %s.\n", conversionOutputFile)
+	}
+	PanicOnError(os.WriteFile(conversionOutputFile, output, publicReadPermissions))
+}
+
+func conversionInTextType(text string) InTextType {
+	switch tmpInTextType {
+	case IText:
+		return IText
+	case IJson:
+		return IJson
+	case "":
+		if strings.HasPrefix(strings.TrimSpace(text), "{") {
+			return IJson
+		}
+		return IText
+	default:
+		PanicWithMessage(fmt.Sprintf("Unknown input format %s. Expected %s or %s for --in", tmpInTextType, IText, IJson))
+		return ""
+	}
+}
+
+func conversionOutTextType() OutTextType {
+	switch tmpOutTextType {
+	case "":
+		return OText
+	case OText, OJsonDense, OJsonPretty:
+		return OutTextType(tmpOutTextType)
+	default:
+		PanicWithMessage(fmt.Sprintf("Unknown output format %s. Expected %s, %s or %s for --out", tmpOutTextType, OText, OJsonDense, OJsonPretty))
+		return ""
+	}
+}
+
+func formatBinary(binary []byte) []byte {
+	ensureBinaryFormatIsKnown(conversionBinaryFormat, "--binary-format")
+	if conversionBinaryFormat == BinaryRaw {
+		return binary
+	}
+	return append(encodeBinaryAs(binary, conversionBinaryFormat), '\n')
+}
+
+func parseBinary(input []byte) []byte {
+	ensureBinaryFormatIsKnown(conversionBinaryFormat, "--binary-format")
+	binary, err := decodeBinaryAs(input, conversionBinaryFormat)
+	PanicWithMessageOnError(err, func() string { return "Could not decode the input as " + conversionBinaryFormat + "." })
+	return binary
+}
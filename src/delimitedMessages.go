@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+/*
+With --delimited, the request and response bodies are streams of messages. Each message is prefixed
+by its length encoded as a varint - as written by writeDelimitedTo in Java or protodelim in Go.
+
+In the Protobuf text format, the messages are separated by lines containing only ---.
+In JSON, the messages simply follow each other. Hence, dense JSON output is NDJSON.
+
+See:
+	https://protobuf.dev/programming-guides/techniques/#streaming
+	https://pkg.go.dev/google.golang.org/protobuf/encoding/protodelim
+*/
+
+const textMessageSeparator = "---"
+
+func textToDelimitedBinary(messageType string, text string, registry *protoregistry.Files) []byte {
+	var messages [][]byte
+	for _, messageText := range splitTextMessages(text, CurrentConfig.InTextType) {
+		binary, _ := textToMsgAndBinary(messageType, messageText, registry)
+		messages = append(messages, binary)
+	}
+	return joinDelimitedBinary(messages)
+}
+
+func delimitedBinaryToText(messageType string, binary []byte, outFormat OutTextType, registry *protoregistry.Files) string {
+	var texts []string
+	for _, message := range splitDelimitedBinary(binary) {
+		text, _ := protoBinaryToMsgAndText(messageType, message, outFormat, registry)
+		texts = append(texts, text)
+	}
+	return joinTextMessages(texts, outFormat)
+}
+
+// Shows the response stream with each message decoded against the response type.
+func decodeDelimitedResponse(responseBinary []byte, responseHeaders string, registry *protoregistry.Files) {
+	displayResponseHeadersAndBinary(responseBinary, responseHeaders)
+
+	responseText := delimitedBinaryToText(properResponseTypeIfProvidedOrEmptyType(), responseBinary, CurrentConfig.OutTextType, registry)
+
+	displayDecodedResponse("Response", CurrentConfig.OutTextType, responseText)
+}
+
+func splitDelimitedBinary(binary []byte) [][]byte {
+	messages := [][]byte{}
+	for len(binary) > 0 {
+		length, n := protowire.ConsumeVarint(binary)
+		if n < 0 {
+			PanicWithMessage(fmt.Sprintf("Could not read the length of message %d of the delimited stream. Error: %s", len(messages)+1, protowire.ParseError(n)))
+		}
+		binary = binary[n:]
+
+		if length > uint64(len(binary)) {
+			PanicWithMessage(fmt.Sprintf("Message %d of the delimited stream is truncated. Expected %d bytes, got %d bytes.", len(messages)+1, length, len(binary)))
+		}
+		messages = append(messages, binary[:length])
+		binary = binary[length:]
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Found %d messages in the delimited stream.\n", len(messages))
+	}
+	return messages
+}
+
+func joinDelimitedBinary(messages [][]byte) []byte {
+	binary := []byte{}
+	for _, message := range messages {
+		binary = protowire.AppendVarint(binary, uint64(len(message)))
+		binary = append(binary, message...)
+	}
+	return binary
+}
+
+// A blank text contains no messages.
+func splitTextMessages(text string, inTextType InTextType) []string {
+	messages := []string{}
+	if strings.TrimSpace(text) == "" {
+		return messages
+	}
+
+	if inTextType == IJson {
+		decoder := json.NewDecoder(strings.NewReader(text))
+		for {
+			var message json.RawMessage
+			err := decoder.Decode(&message)
+			if err == io.EOF {
+				return messages
+			}
+			PanicWithMessageOnError(err, func() string {
+				return fmt.Sprintf("Could not read message %d of the JSON input.", len(messages)+1)
+			})
+			messages = append(messages, string(message))
+		}
+	}
+
+	var current []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == textMessageSeparator {
+			messages = append(messages, strings.Join(current, "\n"))
+			current = nil
+		} else {
+			current = append(current, line)
+		}
+	}
+	return append(messages, strings.Join(current, "\n"))
+}
+
+func joinTextMessages(texts []string, outFormat OutTextType) string {
+	if outFormat == OText {
+		return strings.Join(texts, "\n"+textMessageSeparator+"\n")
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+The diff subcommand decodes two payloads of the same message type and shows the differing fields
+with their paths. The payloads may be given in the Protobuf text format, JSON or binary.
+
+See:
+	messageDiff.go
+*/
+
+var diffMessageType string
+var diffLeftInTextType string
+var diffRightInTextType string
+var diffIgnoredFields []string
+var diffUnorderedLists bool
+
+var diffCmd = &cobra.Command{
+	Short: "Shows the differing fields of two payloads of the same message type.",
+	Use: "diff [flags] -t message-type left right\n\n" +
+		"The payloads left and right are supplied as a string or a filepath (if first character is '@').\n" +
+		"Each difference is shown as a line 'path: left -> right', e.g. misc[1].fooString: \"a\" -> \"b\". Unset fields are shown as " + unsetFieldValue + ".\n" +
+		"In contrast to comparing the text formats, neither the order of the fields nor unset fields with default values produce differences.\n" +
+		"Exits with a non-zero exit code, if differences were found.",
+	Example:               "  protocurl diff -I my-protos -t ..MyResponse --in-right binary @expected.txt @actual.bin",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		propagateProtoFileFlags()
+		registry := convertProtoFilesToProtoRegistryFiles()
+
+		left := decodeDiffPayload(args[0], diffLeftInTextType, registry)
+		right := decodeDiffPayload(args[1], diffRightInTextType, registry)
+
+		differ := MessageDiffer{UnorderedLists: diffUnorderedLists, IgnoredFields: diffIgnoredFields}
+		differences := differ.Compare(left, right)
+		for _, difference := range differences {
+			fmt.Printf("%s: %s -> %s\n", difference.Path, difference.Left, difference.Right)
+		}
+
+		if len(differences) != 0 {
+			PanicWithMessage(fmt.Sprintf("Found %d differences between the payloads.", len(differences)))
+		}
+
+		if CurrentConfig.Verbose {
+			fmt.Println("No differences found.")
+		}
+	},
+}
+
+func initialiseDiffCommand() {
+	flags := diffCmd.Flags()
+	addProtoFileFlags(flags)
+	addVerboseFlag(flags)
+
+	flags.StringVarP(&diffMessageType, "type", "t", "",
+		"Mandatory: Message name or full package path of the Protobuf type of both payloads. The path can be shortened to '..', if the name of the message is unique.")
+	AssertSuccess(diffCmd.MarkFlagRequired("type"))
+
+	flags.StringVar(&diffLeftInTextType, "in-left", "",
+		"The format of the left payload. 'text' uses the Protobuf text format, 'json' uses JSON and 'binary' uses the Protobuf binary format. "+
+			"If not provided, then JSON is inferred, if the first token is a '{' and the text format otherwise.")
+
+	flags.StringVar(&diffRightInTextType, "in-right", "",
+		"The format of the right payload. See --in-left.")
+
+	flags.StringArrayVar(&diffIgnoredFields, "ignore", []string{},
+		"Ignores the field given by its `path` without indices or keys, e.g. misc.fooString. Can be provided multiple times.")
+
+	flags.BoolVar(&diffUnorderedLists, "unordered", false,
+		"Compares repeated fields regardless of the order of their elements.")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+func decodeDiffPayload(payload string, format string, registry *protoregistry.Files) *dynamicpb.Message {
+	if strings.HasPrefix(payload, "@") {
+		filepath := payload[1:]
+		if CurrentConfig.Verbose {
+			fmt.Printf("Payload will be read from file %s.\n", filepath)
+		}
+		file, err := os.ReadFile(filepath)
+		PanicOnError(err)
+		payload = string(file)
+	}
+
+	if format == "" {
+		if strings.HasPrefix(strings.TrimSpace(payload), "{") {
+			format = IJson
+		} else {
+			format = IText
+		}
+		if CurrentConfig.Verbose {
+			fmt.Printf("Inferred payload format as %s.\n", format)
+		}
+	}
+
+	switch format {
+	case IText, IJson:
+		CurrentConfig.InTextType = InTextType(format)
+		_, msg := textToMsgAndBinary(diffMessageType, payload, registry)
+		return msg
+	case IBinary:
+		_, msg := protoBinaryToMsgAndText(diffMessageType, []byte(payload), OText, registry)
+		return msg
+	default:
+		PanicWithMessage(fmt.Sprintf("Unknown payload format %s. Expected %s, %s or %s.", format, IText, IJson, IBinary))
+		return nil
+	}
+}
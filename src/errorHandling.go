@@ -7,6 +7,29 @@ import (
 	"os"
 )
 
+// Similar to curl --fail, responses with a status code which is not accepted result in the exit code 22.
+const (
+	ExitCodeFailure     = 1
+	ExitCodeHttpFailure = 22
+)
+
+// HttpStatusError signals, that the response status code was not accepted.
+type HttpStatusError struct {
+	message string
+}
+
+func (err *HttpStatusError) Error() string {
+	return err.message
+}
+
+// ExitCodeOf returns the exit code for the recovered panic.
+func ExitCodeOf(recovered interface{}) int {
+	if _, isHttpStatusError := recovered.(*HttpStatusError); isHttpStatusError {
+		return ExitCodeHttpFailure
+	}
+	return ExitCodeFailure
+}
+
 // AssertSuccess Use, when error indicates bug in code. Otherwise, use the other functions
 func AssertSuccess(err error) {
 	if err != nil {
@@ -35,3 +58,7 @@ func PanicWithMessageOnError(err error, lazyMessage func() string) {
 		panic(interface{}(lazyMessage() + "\nUnderlying error: " + err.Error()))
 	}
 }
+
+func PanicDueToHttpStatus(message string) {
+	panic(interface{}(&HttpStatusError{message}))
+}
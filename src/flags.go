@@ -2,15 +2,21 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/spf13/pflag"
 )
 
 type InTextType string
 
 const (
-	IText = "text"
-	IJson = "json"
+	IText   = "text"
+	IJson   = "json"
+	IBinary = "binary"
 )
 
 type OutTextType string
@@ -36,33 +42,56 @@ func displayOut(outText OutTextType) string {
 }
 
 var explicitlySupportedMethods = map[string]bool{
-	"GET":  true,
-	"POST": true,
+	"GET":    true,
+	"POST":   true,
+	"PUT":    true,
+	"PATCH":  true,
+	"DELETE": true,
+	"HEAD":   true,
 }
 
 var tmpInTextType string
 var tmpOutTextType string
 var tmpDataTextInferredType InTextType
+var tmpMethodExplicitlyProvided bool
+var tmpDataMayBePipedViaStdin bool
 
 const inferredMessagePathPrefix = ".."
 
 func intialiseFlags() {
-	var flags = rootCmd.Flags()
+	addFlags(rootCmd.Flags())
+	AssertSuccess(rootCmd.MarkFlagRequired("url"))
+
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
+	initialiseShellCommand()
+	initialiseRunCommand()
+	initialiseDiffCommand()
+	initialiseConversionCommands()
+	initialiseDescriptionCommands()
+	initialiseSkeletonCommand()
+	initialiseRandomCommand()
+}
+
+// Adds all flags for a request. These are used by the root command as well as subcommands reusing the request workflow.
+func addFlags(flags *pflag.FlagSet) {
 	// Note. If the long / short name of the arguments are changed, then the Usage and Docs need to be checked for the argument.
 	// It may be mentioned there and their mention needs to be updated.
 
-	flags.StringVarP(&CurrentConfig.ProtoFilesDir, "proto-dir", "I", "/proto",
-		"Uses the specified directory to find the proto-file.")
+	addProtoFileFlags(flags)
+	addDelimitedFlag(flags)
 
-	flags.StringVarP(&CurrentConfig.ProtoInputFilePath, "proto-file", "f", "",
-		"Uses the specified file path to find the Protobuf definition of the message types within 'proto-dir' (relative file path).")
+	flags.StringVarP(&CurrentConfig.Method, "method", "X", "POST",
+		"HTTP request method. GET, POST, PUT, PATCH, DELETE and HEAD are explicitly supported. Other methods are passed on optimistically.")
 
-	flags.BoolVarP(&CurrentConfig.InferProtoFiles, "infer-files", "F", false,
-		"Infer the correct files containing the relevant protobuf messages. All proto files in the proto directory provided by -I will be used. If no -f <file> is provided, this -F is set and the files are inferred.")
+	flags.StringVar(&CurrentConfig.GrpcMethod, "grpc", "",
+		"Invokes the unary gRPC method given as package.Service/Method via HTTP/2 on the server given by -u. "+
+			"The request and response types are inferred from the method, if -i and -o are not provided. Uses the internal http implementation.")
 
-	flags.StringVarP(&CurrentConfig.Method, "method", "X", "POST",
-		"HTTP request method. POST and GET are explicitly supported. Other methods are passed on to curl optimistically.")
+	flags.StringVar(&CurrentConfig.RpcMethod, "rpc", "",
+		"Infers the request and response types from the given method of a service (package.Service/Method or ..Method), if -i and -o are not provided. "+
+			"If the method has a google.api.http annotation, then the http method (unless -X is provided) and the url path appended to -u are taken from it. "+
+			"The fields outside of the path and body are sent as query parameters. Additional bindings are ignored.")
 
 	flags.StringVarP(&CurrentConfig.RequestType, "request-type", "i", "",
 		"Message name or full package path of the Protobuf request type. The path can be shortened to '..', if the name of the request message is unique. Mandatory for POST requests. E.g. mypackage.MyRequest or ..MyRequest")
@@ -71,11 +100,21 @@ func intialiseFlags() {
 		"The Protobuf response type. See -i <request-type>. Overrides --decode-raw. If not set, then --decode-raw is used.")
 
 	flags.BoolVar(&CurrentConfig.DecodeRawResponse, "decode-raw", false,
-		"Decode the response into textual format without the schema by only showing field numbers and inferred field types. Types may be incorrect. Only output format "+string(OText)+" is supported. Use -o <response-type> to see correct contents.")
+		"Decode the response without the schema by only showing field numbers and inferred field types. Nested messages are recognised and alternative readings of numbers are shown as comments in the text format. Types may be incorrect. Use -o <response-type> to see correct contents.")
+
+	flags.BoolVar(&CurrentConfig.SuggestTypes, "suggest-types", false,
+		"Scores all message types of the .proto files against the binary response and shows the best matching ones. "+
+			"Useful to find the response type, if it is unknown. The score counts matching field numbers, wire types and valid strings.")
+
+	flags.IntVar(&CurrentConfig.FuzzRequests, "fuzz", 0,
+		"Sends the given `number` of requests with random payloads of the request type instead of -d and summarises the status codes of the responses. "+
+			"The payloads are controlled via the --fuzz-* flags. Exits with an error, if any status code is not accepted.")
+	addRandomPayloadFlags(flags, "fuzz-")
 
 	flags.StringVar(&tmpInTextType, "in", "",
 		"Specifies, in which format the input -d should be interpreted in. 'text' (default) uses the Protobuf text format and 'json' uses JSON. "+
-			"The type is inferred as JSON if the first token is a '{'.")
+			"The type is inferred as JSON if the first token is a '{'. 'binary' uses the Protobuf binary format and is never inferred. "+
+			"It is meant for files and stdin, e.g. '--in binary -d @request.bin'.")
 
 	flags.StringVar(&tmpOutTextType, "out", "",
 		"Produces the output in the specified format. 'text' (default) produces Protobuf text format. 'json' produces dense JSON and "+
@@ -84,25 +123,90 @@ func intialiseFlags() {
 
 	flags.StringVarP(&CurrentConfig.Url, "url", "u", "",
 		"Mandatory: The url to send the request to")
-	AssertSuccess(rootCmd.MarkFlagRequired("url"))
 
 	flags.StringVarP(&CurrentConfig.DataText, "data-text-or-file", "d", "",
 		"The payload data in Protobuf text format or JSON supplied as a string or a filepath (if first character is '@'). "+
+			"With '@-' the payload is read from stdin. If -d is absent and a request type is known, then a payload piped via stdin is used. Files redirected to stdin are only read via @-. "+
 			"The string is inferred from the input as JSON if the first token is a '{'."+
 			"The format can be set explicitly via --in. Mandatory if request-type is provided."+
 			"See "+GithubRepositoryLink)
 
+	flags.StringVar(&CurrentConfig.ExpectedResponse, "expect", "",
+		"The expected response in Protobuf text format or JSON supplied as a string or a filepath (if first character is '@'). "+
+			"If the decoded response differs, then the differing fields are shown and protocurl exits with an error. Needs the response type.")
+
+	flags.BoolVar(&CurrentConfig.ExpectPartialMatch, "expect-partial", false,
+		"Only compares the fields set in --expect with the response. Other fields of the response are ignored.")
+
+	flags.IntVar(&CurrentConfig.ExpectedStatusCode, "expect-status", 0,
+		"Expects the given HTTP status `code` instead of any 2XX status code.")
+
+	flags.IntSliceVar(&CurrentConfig.AcceptedStatusCodes, "accept-status", []int{},
+		"Accepts the given response status `codes` in addition to any 2XX status code. Other status codes result in the exit code "+strconv.Itoa(ExitCodeHttpFailure)+".")
+
+	flags.StringVar(&CurrentConfig.ErrorType, "error-type", "",
+		"The Protobuf message type of the error response bodies. If the status code is not accepted, then the response is decoded and shown with it. See -i <request-type>.")
+
+	flags.StringArrayVar(&CurrentConfig.StatusContentTypes, "status-content-type", []string{},
+		"Decodes responses with the Content-Type `type` as google.rpc.Status and unpacks its details. "+
+			"Failed responses are decoded as google.rpc.Status without it, if they are recognised as such. Can be provided multiple times.")
+
+	flags.StringArrayVar(&CurrentConfig.ExpectedHeaders, "expect-header", []string{},
+		"Expects the response to have the `header` 'Name: value'. Can be provided multiple times.")
+
 	flags.BoolVarP(&CurrentConfig.NoDefaultHeaders, "no-default-headers", "n", false,
-		"Default headers (e.g. \"Content-Type\") will not be passed to curl. Assumes --curl. Use \"-n -H 'Content-Type: FooBar'\" to override the default content type.")
+		"Default headers (e.g. \"Content-Type\") will not be sent. Use \"-n -H 'Content-Type: FooBar'\" to override the default content type.")
 
 	flags.StringArrayVarP(&CurrentConfig.RequestHeaders, "request-header", "H", []string{},
-		"Adds the `string` header to the invocation of cURL. This option is not supported when --no-curl is active. E.g. -H 'MyHeader: FooBar'.")
+		"Adds the `string` header to the request. Can be provided multiple times. E.g. -H 'MyHeader: FooBar'.")
 
-	flags.BoolVar(&CurrentConfig.GlobalProtoc, "protoc", false,
-		"Forces the use of a global protoc executable found in PATH or via --protoc-path instead of using the bundled one. If none was found, then exits with an error.")
+	flags.StringVar(&CurrentConfig.CaCertFile, "cacert", "",
+		"Verifies the server certificate with the CA certificates in the PEM `file` instead of the system CA certificates.")
 
-	flags.StringVar(&CurrentConfig.CustomProtocPath, "protoc-path", "",
-		"Uses the given path to invoke protoc instead of searching for "+ProtocExecutableName+" in PATH. Also activates --protoc.")
+	flags.StringVar(&CurrentConfig.ClientCertFile, "cert", "",
+		"Authenticates with the client certificate in the PEM `file` (mutual TLS). Needs --key.")
+
+	flags.StringVar(&CurrentConfig.ClientKeyFile, "key", "",
+		"The private key in the PEM `file` belonging to the client certificate given via --cert.")
+
+	flags.BoolVar(&CurrentConfig.InsecureSkipVerify, "insecure", false,
+		"Skips the verification of the server certificate. Only use this for testing.")
+
+	flags.StringVar(&CurrentConfig.TlsServerName, "tls-server-name", "",
+		"Uses the given `name` for SNI and the verification of the server certificate instead of the host of the url. Uses the internal http implementation.")
+
+	flags.StringVar(&CurrentConfig.RequestEncoding, "request-encoding", BinaryRaw,
+		"The `encoding` of the request body. '"+BinaryRaw+"' sends the binary Protobuf payload as it is. '"+BinaryHex+"', '"+BinaryBase64+"' and '"+BinaryBase64Url+"' send it in the respective encoding.")
+
+	flags.StringVar(&CurrentConfig.ResponseEncoding, "response-encoding", BinaryRaw,
+		"The `encoding` of the response body. See --request-encoding. A JSON string around the encoded payload is removed before decoding.")
+
+	flags.StringVar(&CurrentConfig.WireFormat, "wire-format", WireBinary,
+		"The `format` of the request body on the wire. '"+WireBinary+"' sends the Protobuf binary format as "+wireFormatContentTypes[WireBinary]+". '"+WireJson+"' sends JSON as "+wireFormatContentTypes[WireJson]+" "+
+			"and '"+WireText+"' sends the Protobuf text format as "+wireFormatContentTypes[WireText]+". The response is decoded according to its Content-Type. The input and output are still given and shown via --in and --out. "+
+			"Without -o <response-type>, a JSON or text response is shown as received.")
+
+	flags.StringVar(&CurrentConfig.StreamFormat, "stream", "",
+		"Reads the response as a stream of frames in the given `format` and shows each decoded frame as soon as it arrives. '"+StreamSse+"' reads server-sent events whose data lines carry base64 encoded messages. "+
+			"'"+StreamBase64Lines+"' reads one base64 encoded message per line. '"+StreamDelimited+"' reads messages prefixed by their varint-encoded length. Uses the internal http implementation.")
+
+	flags.Float64Var(&CurrentConfig.ConnectTimeoutSeconds, "connect-timeout", 0,
+		"Maximum time in `seconds` to establish the connection of each attempt. 0 means no timeout.")
+
+	flags.Float64Var(&CurrentConfig.MaxTimeSeconds, "max-time", 0,
+		"Maximum time in `seconds` for each attempt including the response. 0 means no timeout.")
+
+	flags.IntVar(&CurrentConfig.MaxAttempts, "max-attempts", 1,
+		"Maximum `number` of attempts of the request. Attempts failing with --retry-status or --retry-errors are retried.")
+
+	flags.Float64Var(&CurrentConfig.RetryDelaySeconds, "retry-delay", 1,
+		"Delay in `seconds` before the first retry. The delay doubles after each retry.")
+
+	flags.IntSliceVar(&CurrentConfig.RetryStatusCodes, "retry-status", []int{429, 502, 503, 504},
+		"Response status `codes` which are retried. A status code expected via --expect-status is not retried.")
+
+	flags.StringSliceVar(&CurrentConfig.RetryErrors, "retry-errors", []string{RetryOnConnect, RetryOnTimeout},
+		"Network `errors` which are retried. '"+RetryOnConnect+"' retries failures to resolve the host or to connect. '"+RetryOnTimeout+"' retries exceeded timeouts.")
 
 	flags.BoolVar(&CurrentConfig.ForceCurl, "curl", false,
 		"Forces the use of curl executable found in PATH. If none was found, then exits with an error.")
@@ -116,8 +220,7 @@ func intialiseFlags() {
 	flags.StringVarP(&CurrentConfig.AdditionalCurlArgs, "curl-args", "C", "",
 		"Additional cURL args which will be passed on to cURL during request invocation for further configuration. Also activates --curl.")
 
-	flags.BoolVarP(&CurrentConfig.Verbose, "verbose", "v", false,
-		"Prints version and enables verbose output. Also activates -D.")
+	addVerboseFlag(flags)
 
 	flags.BoolVarP(&CurrentConfig.DisplayBinaryAndHttp, "display-binary-and-http", "D", false,
 		"Displays the binary request and response as well as the non-binary response headers.")
@@ -131,24 +234,64 @@ func intialiseFlags() {
 			"Errors are still printed to stderr.")
 }
 
+// Adds the flags determining how the .proto files are converted to the proto registry.
+func addProtoFileFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&CurrentConfig.ProtoFilesDir, "proto-dir", "I", "/proto",
+		"Uses the specified directory to find the proto-file.")
+
+	flags.StringVarP(&CurrentConfig.ProtoInputFilePath, "proto-file", "f", "",
+		"Uses the specified file path to find the Protobuf definition of the message types within 'proto-dir' (relative file path).")
+
+	flags.BoolVarP(&CurrentConfig.InferProtoFiles, "infer-files", "F", false,
+		"Infer the correct files containing the relevant protobuf messages. All proto files in the proto directory provided by -I will be used. If no -f <file> is provided, this -F is set and the files are inferred.")
+
+	flags.BoolVar(&CurrentConfig.GlobalProtoc, "protoc", false,
+		"Forces the use of a global protoc executable found in PATH or via --protoc-path instead of using the bundled one. If none was found, then exits with an error.")
+
+	flags.StringVar(&CurrentConfig.CustomProtocPath, "protoc-path", "",
+		"Uses the given path to invoke protoc instead of searching for "+ProtocExecutableName+" in PATH. Also activates --protoc.")
+
+	flags.BoolVar(&CurrentConfig.ForceNoProtoc, "no-protoc", false,
+		"Forces the use of the built-in .proto compiler instead of protoc. Neither a bundled nor a global protoc is needed then.")
+
+	flags.StringArrayVar(&CurrentConfig.DescriptorSetFiles, "descriptor-set", []string{},
+		"Uses the binary FileDescriptorSet in the given `file` as the source of the Protobuf definitions instead of the .proto files. "+
+			"It can be created via '"+ProtocExecutableName+" --include_imports -o <file> ...'. Can be provided multiple times. -I and -f are not used then. "+
+			"Cannot be combined with --protoc, --protoc-path or --no-protoc.")
+}
+
+func addDelimitedFlag(flags *pflag.FlagSet) {
+	flags.BoolVar(&CurrentConfig.Delimited, "delimited", false,
+		"Treats the request and response bodies as streams of messages, each prefixed by its varint-encoded length. "+
+			"In the text format, the messages are separated by lines containing only "+textMessageSeparator+". In JSON, they follow each other, e.g. as NDJSON.")
+}
+
+func addVerboseFlag(flags *pflag.FlagSet) {
+	flags.BoolVarP(&CurrentConfig.Verbose, "verbose", "v", false,
+		"Prints version and enables verbose output. Also activates -D.")
+}
+
 func propagateFlags() {
 
-	if CurrentConfig.Verbose {
-		CurrentConfig.DisplayBinaryAndHttp = true
-	}
+	propagateOutputFlags()
 
-	if CurrentConfig.ShowOutputOnly {
-		CurrentConfig.Verbose = false
-		CurrentConfig.DisplayBinaryAndHttp = false
+	if CurrentConfig.GrpcMethod != "" && CurrentConfig.RpcMethod != "" {
+		PanicWithMessage("Both --grpc and --rpc are provided. Please provide only one of these.")
 	}
 
-	if CurrentConfig.SilentMode {
-		CurrentConfig.Verbose = false
-		CurrentConfig.DisplayBinaryAndHttp = false
-		CurrentConfig.ShowOutputOnly = false
+	if CurrentConfig.GrpcMethod != "" {
+		if CurrentConfig.Method != "POST" {
+			PanicWithMessage("gRPC requests always use the method POST. Got: " + CurrentConfig.Method)
+		}
+		if CurrentConfig.ForceCurl || len(CurrentConfig.AdditionalCurlArgs) != 0 || CurrentConfig.CustomCurlPath != "" {
+			PanicWithMessage("gRPC requests are only supported with the internal http implementation. Please avoid --curl, --curl-path and -C.")
+		}
+		if CurrentConfig.Delimited {
+			PanicWithMessage("gRPC requests use their own framing of messages. Please avoid --delimited.")
+		}
 	}
 
-	if CurrentConfig.ResponseType == "" && !CurrentConfig.DecodeRawResponse {
+	if CurrentConfig.ResponseType == "" && !CurrentConfig.DecodeRawResponse && !typesAreInferredFromMethod() {
 		CurrentConfig.DecodeRawResponse = true
 		if CurrentConfig.Verbose {
 			fmt.Println("Response type (-o) was not provided, hence --decode-raw will be used.")
@@ -165,13 +308,28 @@ func propagateFlags() {
 		fmt.Printf("Got method %s which is not explicitly supported. Proceeding optimistically.", CurrentConfig.Method)
 	}
 
-	if CurrentConfig.Method == "POST" {
+	if CurrentConfig.Method == "POST" && !typesAreInferredFromMethod() {
 		if CurrentConfig.RequestType == "" {
 			PanicWithMessage("With method POST, a request type and the data text is needed. However, request type was not provided. Aborting.")
 		}
 	}
 
-	if strings.HasPrefix(CurrentConfig.DataText, "@") {
+	if CurrentConfig.FuzzRequests != 0 {
+		ensureFuzzFlagsAreValid()
+	}
+
+	if CurrentConfig.DataText == "@-" {
+		if CurrentConfig.Verbose {
+			fmt.Println("Input text will be read from stdin.")
+		}
+		CurrentConfig.DataText = readDataFromStdin()
+	} else if CurrentConfig.DataText == "" && tmpDataMayBePipedViaStdin && CurrentConfig.FuzzRequests == 0 && stdinIsPiped() &&
+		(CurrentConfig.RequestType != "" || typesAreInferredFromMethod()) {
+		if CurrentConfig.Verbose {
+			fmt.Println("Data text (-d) was not provided, hence the input text will be read from the piped stdin.")
+		}
+		CurrentConfig.DataText = readDataFromStdin()
+	} else if strings.HasPrefix(CurrentConfig.DataText, "@") {
 		filepath := CurrentConfig.DataText[1:]
 		if CurrentConfig.Verbose {
 			fmt.Printf("Input text will be read from file %s.\n", filepath)
@@ -181,16 +339,40 @@ func propagateFlags() {
 		CurrentConfig.DataText = string(file) // assumes UTF-8
 	}
 
-	if CurrentConfig.DataText != "" && CurrentConfig.RequestType == "" {
+	if strings.HasPrefix(CurrentConfig.ExpectedResponse, "@") {
+		filepath := CurrentConfig.ExpectedResponse[1:]
+		if CurrentConfig.Verbose {
+			fmt.Printf("Expected response will be read from file %s.\n", filepath)
+		}
+		file, err := os.ReadFile(filepath)
+		PanicOnError(err)
+		CurrentConfig.ExpectedResponse = string(file) // assumes UTF-8
+	}
+
+	if CurrentConfig.ExpectedResponse != "" && CurrentConfig.ResponseType == "" && !typesAreInferredFromMethod() {
+		PanicWithMessage("An expected response (--expect) was provided, but no response type was given. Hence, the response cannot be compared.")
+	}
+
+	if CurrentConfig.ExpectedResponse != "" && CurrentConfig.Delimited {
+		PanicWithMessage("An expected response (--expect) cannot be compared with a delimited stream of messages (--delimited).")
+	}
+
+	if CurrentConfig.ExpectPartialMatch && CurrentConfig.ExpectedResponse == "" {
+		PanicWithMessage("--expect-partial was provided without an expected response via --expect.")
+	}
+
+	if CurrentConfig.DataText != "" && CurrentConfig.RequestType == "" && !typesAreInferredFromMethod() {
 		PanicWithMessage("Non-empty data-body was provided, but no request type was given. Hence, encoding of data-body is not possible.")
 	}
 
-	if strings.HasPrefix(strings.TrimSpace(CurrentConfig.DataText), "{") {
+	if tmpInTextType == IBinary {
+		tmpDataTextInferredType = IBinary // the binary format cannot be inferred reliably
+	} else if strings.HasPrefix(strings.TrimSpace(CurrentConfig.DataText), "{") {
 		tmpDataTextInferredType = IJson
 	} else {
 		tmpDataTextInferredType = IText
 	}
-	if CurrentConfig.Verbose {
+	if CurrentConfig.Verbose && tmpDataTextInferredType != IBinary {
 		fmt.Printf("Inferred input text type as %s.\n", tmpDataTextInferredType)
 	}
 
@@ -198,8 +380,10 @@ func propagateFlags() {
 		CurrentConfig.InTextType = IText
 	} else if tmpInTextType == IJson {
 		CurrentConfig.InTextType = IJson
+	} else if tmpInTextType == IBinary {
+		CurrentConfig.InTextType = IBinary
 	} else if tmpInTextType != "" {
-		PanicWithMessage(fmt.Sprintf("Unknown input format %s. Expected %s or %s for --in", tmpInTextType, IText, IJson))
+		PanicWithMessage(fmt.Sprintf("Unknown input format %s. Expected %s, %s or %s for --in", tmpInTextType, IText, IJson, IBinary))
 	} else {
 		CurrentConfig.InTextType = tmpDataTextInferredType
 	}
@@ -217,6 +401,8 @@ func propagateFlags() {
 		CurrentConfig.OutTextType = OJsonPretty
 	} else if tmpOutTextType != "" {
 		PanicWithMessage(fmt.Sprintf("Unknown output format %s. Expected %s, %s or %s for --out", tmpOutTextType, OText, OJsonDense, OJsonPretty))
+	} else if CurrentConfig.InTextType == IBinary {
+		CurrentConfig.OutTextType = OText
 	} else {
 		CurrentConfig.OutTextType = OutTextType(tmpDataTextInferredType)
 	}
@@ -225,14 +411,127 @@ func propagateFlags() {
 		CurrentConfig.ForceCurl = true
 	}
 
-	if CurrentConfig.CustomProtocPath != "" {
-		CurrentConfig.GlobalProtoc = true
+	if CurrentConfig.TlsServerName != "" {
+		CurrentConfig.ForceNoCurl = true
+	}
+
+	ensureBinaryFormatIsKnown(CurrentConfig.RequestEncoding, "--request-encoding")
+	ensureBinaryFormatIsKnown(CurrentConfig.ResponseEncoding, "--response-encoding")
+	if CurrentConfig.GrpcMethod != "" && (CurrentConfig.RequestEncoding != BinaryRaw || CurrentConfig.ResponseEncoding != BinaryRaw) {
+		PanicWithMessage("gRPC requests always use the raw binary format. Please avoid --request-encoding and --response-encoding.")
+	}
+
+	ensureWireFormatIsKnown(CurrentConfig.WireFormat)
+	if CurrentConfig.GrpcMethod != "" && CurrentConfig.WireFormat != WireBinary {
+		PanicWithMessage("gRPC requests always use the binary wire format. Please avoid --wire-format.")
+	}
+	if CurrentConfig.Delimited && CurrentConfig.WireFormat != WireBinary {
+		PanicWithMessage("A delimited stream of messages (--delimited) is always sent in the binary wire format. Please avoid --wire-format.")
+	}
+
+	if CurrentConfig.SuggestTypes && (CurrentConfig.Delimited || CurrentConfig.StreamFormat != "") {
+		PanicWithMessage("Message types can only be suggested for a single response. Please avoid --delimited and --stream.")
+	}
+
+	if CurrentConfig.StreamFormat != "" {
+		if !slices.Contains(streamFormats, CurrentConfig.StreamFormat) {
+			PanicWithMessage(fmt.Sprintf("Unknown stream format %s. Expected %s, %s or %s for --stream", CurrentConfig.StreamFormat, StreamSse, StreamBase64Lines, StreamDelimited))
+		}
+		if CurrentConfig.GrpcMethod != "" {
+			PanicWithMessage("gRPC requests cannot be read as a stream of frames. Please avoid --stream.")
+		}
+		if CurrentConfig.ExpectedResponse != "" {
+			PanicWithMessage("An expected response (--expect) cannot be compared with a stream of frames (--stream).")
+		}
+		if CurrentConfig.ResponseEncoding != BinaryRaw {
+			PanicWithMessage("The frames of a stream (--stream) have their own encoding. Please avoid --response-encoding.")
+		}
+		CurrentConfig.ForceNoCurl = true
+	}
+
+	if CurrentConfig.MaxAttempts < 1 {
+		PanicWithMessage("At least one attempt is needed for --max-attempts.")
+	}
+
+	if CurrentConfig.ConnectTimeoutSeconds < 0 || CurrentConfig.MaxTimeSeconds < 0 || CurrentConfig.RetryDelaySeconds < 0 {
+		PanicWithMessage("The timeouts and the retry delay must not be negative.")
+	}
+
+	for _, retryError := range CurrentConfig.RetryErrors {
+		if !slices.Contains(retryableErrorKinds, retryError) {
+			PanicWithMessage(fmt.Sprintf("Unknown network error %s. Expected %s for --retry-errors", retryError, strings.Join(retryableErrorKinds, " or ")))
+		}
+	}
+
+	if (CurrentConfig.ClientCertFile == "") != (CurrentConfig.ClientKeyFile == "") {
+		PanicWithMessage("The client certificate and its key need to be provided together via --cert and --key.")
 	}
 
 	if CurrentConfig.ForceCurl && CurrentConfig.ForceNoCurl {
 		PanicWithMessage("Both --curl and --no-curl are active.\nI cannot use and not use curl.\nPlease check the supplied and implied arguments via -v.")
 	}
 
+	propagateProtoFileFlags()
+}
+
+func ensureFuzzFlagsAreValid() {
+	if CurrentConfig.FuzzRequests < 0 {
+		PanicWithMessage(fmt.Sprintf("The number of requests for --fuzz must not be negative. Got: %d", CurrentConfig.FuzzRequests))
+	}
+	ensureRandomPayloadFlagsAreValid()
+
+	if CurrentConfig.RequestType == "" {
+		PanicWithMessage("Random payloads need a request type. Please provide -i.")
+	}
+	if CurrentConfig.DataText != "" {
+		PanicWithMessage("The payloads are generated randomly with --fuzz. Please avoid -d.")
+	}
+	if CurrentConfig.GrpcMethod != "" || CurrentConfig.RpcMethod != "" {
+		PanicWithMessage("Random payloads are sent to the url given by -u. Please avoid --grpc and --rpc together with --fuzz.")
+	}
+	if CurrentConfig.Delimited || CurrentConfig.StreamFormat != "" || CurrentConfig.SuggestTypes || hasResponseExpectations() {
+		PanicWithMessage("Only the status codes of the responses are summarised with --fuzz. Please avoid --delimited, --stream, --suggest-types, --expect and --expect-header.")
+	}
+	if CurrentConfig.RandomMalformed && CurrentConfig.WireFormat != WireBinary {
+		PanicWithMessage("Malformed payloads can only be sent in the binary wire format. Please avoid --wire-format together with --fuzz-malformed.")
+	}
+}
+
+func propagateOutputFlags() {
+	if CurrentConfig.Verbose {
+		CurrentConfig.DisplayBinaryAndHttp = true
+	}
+
+	if CurrentConfig.ShowOutputOnly {
+		CurrentConfig.Verbose = false
+		CurrentConfig.DisplayBinaryAndHttp = false
+	}
+
+	if CurrentConfig.SilentMode {
+		CurrentConfig.Verbose = false
+		CurrentConfig.DisplayBinaryAndHttp = false
+		CurrentConfig.ShowOutputOnly = false
+	}
+}
+
+// Propagates the flags which determine how the .proto files are converted to the proto registry.
+func propagateProtoFileFlags() {
+	if CurrentConfig.CustomProtocPath != "" {
+		CurrentConfig.GlobalProtoc = true
+	}
+
+	if CurrentConfig.GlobalProtoc && CurrentConfig.ForceNoProtoc {
+		PanicWithMessage("Both --protoc and --no-protoc are active.\nI cannot use and not use protoc.\nPlease check the supplied and implied arguments via -v.")
+	}
+
+	if len(CurrentConfig.DescriptorSetFiles) != 0 && (CurrentConfig.GlobalProtoc || CurrentConfig.ForceNoProtoc) {
+		PanicWithMessage("Both --descriptor-set and --protoc, --protoc-path or --no-protoc are provided. The descriptor set is used without compiling .proto files. Please avoid the latter.")
+	}
+
+	if len(CurrentConfig.DescriptorSetFiles) != 0 && CurrentConfig.ProtoInputFilePath != "" {
+		PanicWithMessage("Both --descriptor-set and -f <file> are provided. Please provide only one of these.")
+	}
+
 	if CurrentConfig.InferProtoFiles && CurrentConfig.ProtoInputFilePath != "" {
 		PanicWithMessage("Both -F is set and -f <file> is provided. Please provide only one of these.")
 	}
@@ -243,16 +542,21 @@ func propagateFlags() {
 			fmt.Printf("Infering proto files (-F), since -f <file> was not provided.\n")
 		}
 	}
+}
 
-	if CurrentConfig.DecodeRawResponse && (strings.Contains(string(CurrentConfig.OutTextType), "json")) {
-		PanicWithMessage("Decoding of raw messages is not supported with output format " + string(CurrentConfig.OutTextType) + ". Please use " + string(OText) + " instead.")
-	}
+func typesAreInferredFromMethod() bool {
+	return CurrentConfig.GrpcMethod != "" || CurrentConfig.RpcMethod != ""
+}
 
-	if CurrentConfig.ForceNoCurl && len(CurrentConfig.RequestHeaders) != 0 {
-		PanicDueToUnsupportedHeadersWhenInternalHttp(CurrentConfig.RequestHeaders)
-	}
+// Only pipes are read implicitly. Other stdins such as files or /dev/null are left alone, since they may not be meant
+// as the payload, e.g. within a 'while read' loop.
+func stdinIsPiped() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeNamedPipe != 0
 }
 
-func PanicDueToUnsupportedHeadersWhenInternalHttp(headers []string) {
-	PanicWithMessage(fmt.Sprintf("Non-default or custom headers are not supported when  using internal http. Please provide curl in path and avoid using --no-curl. Found headers: %+q", headers))
+func readDataFromStdin() string {
+	input, err := io.ReadAll(os.Stdin)
+	PanicOnError(err)
+	return string(input) // assumes UTF-8 unless --in binary is used
 }
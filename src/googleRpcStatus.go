@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+Many APIs respond with a google.rpc.Status on failure. Its details are google.protobuf.Any messages
+of the well-known error detail types (e.g. google.rpc.BadRequest) or of the types of the API itself.
+
+The response is decoded as google.rpc.Status, if its Content-Type matches one of --status-content-type
+or if the response failed and its body is recognised as a google.rpc.Status. An explicit --error-type takes precedence.
+The details are unpacked against the loaded .proto files.
+
+google.rpc.Status and the well-known error detail types are taken from the loaded .proto files, if they are part of them.
+Otherwise, the google/rpc .proto files bundled in protocurl-internal/include are compiled and added to the registry.
+
+See:
+	https://github.com/googleapis/googleapis/blob/master/google/rpc/status.proto
+	https://github.com/googleapis/googleapis/blob/master/google/rpc/error_details.proto
+*/
+
+const GoogleRpcStatusMessageType = "google.rpc.Status"
+
+var bundledGoogleRpcProtoFiles = []string{"google/rpc/status.proto", "google/rpc/error_details.proto"}
+
+// Returns false, if google.rpc.Status is neither part of the registry nor bundled.
+func googleRpcStatusDescriptor(registry *protoregistry.Files) (protoreflect.MessageDescriptor, bool) {
+	if _, err := registry.FindDescriptorByName(GoogleRpcStatusMessageType); err != nil {
+		registerBundledGoogleRpcProtoFiles(registry)
+	}
+
+	descriptor, err := registry.FindDescriptorByName(GoogleRpcStatusMessageType)
+	if err != nil {
+		return nil, false
+	}
+	messageDescriptor, isMessage := descriptor.(protoreflect.MessageDescriptor)
+	return messageDescriptor, isMessage
+}
+
+func registerBundledGoogleRpcProtoFiles(registry *protoregistry.Files) {
+	protocurlInternalPath, err := getProtocurlInternalPath()
+	if err != nil {
+		if CurrentConfig.Verbose {
+			fmt.Printf("Cannot use the bundled %s, as the %s directory was not found.\n", GoogleRpcStatusMessageType, ProtocurlInternalName)
+		}
+		return
+	}
+	includePath := filepath.Join(protocurlInternalPath, "include")
+	if _, err := os.Stat(filepath.Join(includePath, bundledGoogleRpcProtoFiles[0])); err != nil {
+		if CurrentConfig.Verbose {
+			fmt.Printf("Cannot use the bundled %s, as %s was not found in %s.\n", GoogleRpcStatusMessageType, bundledGoogleRpcProtoFiles[0], includePath)
+		}
+		return
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Using the bundled %s, as %s is not part of the .proto files.\n", strings.Join(bundledGoogleRpcProtoFiles, " and "), GoogleRpcStatusMessageType)
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: []string{includePath}}),
+	}
+	compiledFiles, err := compiler.Compile(context.Background(), bundledGoogleRpcProtoFiles...)
+	PanicWithMessageOnError(err, func() string {
+		return "Failed to compile the bundled " + strings.Join(bundledGoogleRpcProtoFiles, " and ") + " in " + includePath + "."
+	})
+
+	// the imported well-known types are already part of the registry or are resolved from the global registry
+	for _, file := range compiledFiles {
+		if _, err := registry.FindFileByPath(file.Path()); err != nil {
+			_ = registry.RegisterFile(file)
+		}
+	}
+}
+
+func mustGetGoogleRpcStatusDescriptor(registry *protoregistry.Files) protoreflect.MessageDescriptor {
+	descriptor, found := googleRpcStatusDescriptor(registry)
+	if !found {
+		PanicWithMessage("Cannot decode the response as " + GoogleRpcStatusMessageType + ", as it is neither part of the .proto files nor bundled in " +
+			ProtocurlInternalName + "/include. Please add google/rpc/status.proto to the .proto files.")
+	}
+	return descriptor
+}
+
+// True, if the Content-Type of the response matches one of --status-content-type.
+// A configured content type without parameters matches any parameters of the response content type.
+func hasGoogleRpcStatusContentType(responseHeaders string) bool {
+	for _, contentType := range parseResponseHeaders(responseHeaders)["content-type"] {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			continue
+		}
+		for _, statusContentType := range CurrentConfig.StatusContentTypes {
+			if mediaTypeMatches(mediaType, params, statusContentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func mediaTypeMatches(mediaType string, params map[string]string, expectedContentType string) bool {
+	expectedMediaType, expectedParams, err := mime.ParseMediaType(expectedContentType)
+	if err != nil || expectedMediaType != mediaType {
+		return false
+	}
+	for name, value := range expectedParams {
+		if params[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// The body is recognised as a google.rpc.Status, if it decodes without unknown fields,
+// has a non-OK code and all details have a type url.
+func isRecognisedAsGoogleRpcStatus(responseBinary []byte, registry *protoregistry.Files) bool {
+	if len(responseBinary) == 0 {
+		return false
+	}
+	descriptor, found := googleRpcStatusDescriptor(registry)
+	if !found {
+		return false
+	}
+
+	msg := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(responseBinary, msg); err != nil || hasUnknownFields(msg) {
+		return false
+	}
+	fields := descriptor.Fields()
+	if msg.Get(fields.ByName("code")).Int() == 0 { // OK
+		return false
+	}
+	details := msg.Get(fields.ByName("details")).List()
+	for i := 0; i < details.Len(); i++ {
+		detail := details.Get(i).Message()
+		if !strings.Contains(detail.Get(detail.Descriptor().Fields().ByName("type_url")).String(), "/") {
+			return false
+		}
+	}
+	return true
+}
+
+// Decodes the response body as google.rpc.Status and shows it with the given title.
+func decodeGoogleRpcStatus(title string, responseBinary []byte, responseHeaders string, registry *protoregistry.Files) *dynamicpb.Message {
+	displayResponseHeadersAndBinary(responseBinary, responseHeaders)
+
+	statusText, statusMsg, shownFormat := googleRpcStatusToMsgAndText(responseBinary, CurrentConfig.OutTextType, registry)
+
+	if CurrentConfig.Verbose {
+		code := int(statusMsg.Get(statusMsg.Descriptor().Fields().ByName("code")).Int())
+		fmt.Printf("Decoding response as %s with code %s (%d).\n", GoogleRpcStatusMessageType, grpcStatusCodeName(code), code)
+	}
+
+	displayDecodedResponse(title, shownFormat, statusText)
+
+	return statusMsg
+}
+
+// The details are expanded, if their types can be resolved. Otherwise, they are shown with their binary value.
+// As JSON cannot represent unresolved details, the status is shown as text in that case. The shown format is returned.
+func googleRpcStatusToMsgAndText(binary []byte, outFormat OutTextType, registry *protoregistry.Files) (string, *dynamicpb.Message, OutTextType) {
+	msg := dynamicpb.NewMessage(mustGetGoogleRpcStatusDescriptor(registry))
+	PanicOnError(proto.Unmarshal(binary, msg))
+
+	resolver := newRegistryTypeResolver(registry)
+	text, err := msgToText(msg, outFormat, resolver)
+	if err != nil && outFormat != OText {
+		if !CurrentConfig.SilentMode {
+			fmt.Printf("Showing the %s as text, as it cannot be shown as JSON. Error: %s\n", GoogleRpcStatusMessageType, err.Error())
+		}
+		outFormat = OText
+		text, err = msgToText(msg, outFormat, resolver)
+	}
+	PanicOnError(err)
+
+	return text, msg, outFormat
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+/*
+A unary gRPC call is a HTTP/2 POST request to /package.Service/Method where the request and response bodies
+consist of a single length-prefixed message each. The prefix has 5 bytes: 1 byte compression flag and
+4 bytes big-endian message length. The outcome is reported via the grpc-status and grpc-message trailers.
+
+See:
+	https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+	https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
+*/
+
+const grpcMessagePrefixLength = 5
+
+var grpcStatusCodeNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND", "ALREADY_EXISTS",
+	"PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE",
+	"UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+func resolveGrpcMethodAndInferMessageTypes(registry *protoregistry.Files) {
+	method := resolveMethodByName(CurrentConfig.GrpcMethod, registry)
+
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		PanicWithMessage("The gRPC method " + string(method.FullName()) + " uses streaming. Only unary gRPC methods are supported.")
+	}
+
+	// normalise to the notation used in the request path
+	CurrentConfig.GrpcMethod = string(method.Parent().FullName()) + "/" + string(method.Name())
+
+	inferRequestAndResponseTypesFromMethod(method)
+}
+
+func invokeGrpcRequest(requestBinary []byte) ([]byte, string) {
+	if CurrentConfig.Verbose {
+		fmt.Println("Invoking internal gRPC request.")
+	}
+
+	requestUrl := strings.TrimSuffix(CurrentConfig.Url, "/") + "/" + CurrentConfig.GrpcMethod
+	if CurrentConfig.Verbose {
+		fmt.Printf("Using gRPC url: %s\n", requestUrl)
+	}
+
+	client := grpcHttpClient()
+
+	var httpResponse *http.Response
+	body, headersString := invokeWithRetries(func() ([]byte, string, error) {
+		httpRequest, err := http.NewRequest("POST", requestUrl, bytes.NewReader(prependGrpcMessagePrefix(requestBinary)))
+		PanicOnError(err)
+		addRequestHeaders(httpRequest)
+
+		httpResponse, err = client.Do(httpRequest)
+		if err != nil {
+			return nil, "", err
+		}
+		defer func() { _ = httpResponse.Body.Close() }()
+
+		body, err := io.ReadAll(httpResponse.Body) // trailers are only available after the body was read completely
+		if err != nil {
+			return nil, "", err
+		}
+
+		headers, err := httputil.DumpResponse(httpResponse, false)
+		PanicOnError(err)
+		return body, strings.TrimSpace(strings.TrimSpace(string(headers)) + "\n" + formatTrailers(httpResponse.Trailer)), nil
+	}, internalRequestErrorKind, "Failed internal gRPC request.")
+
+	ensureStatusCodeIsAccepted(headersString)
+	ensureGrpcStatusIsOk(httpResponse)
+
+	return extractGrpcMessage(body, httpResponse.Header.Get("Grpc-Encoding")), headersString
+}
+
+func grpcHttpClient() *http.Client {
+	var protocols http.Protocols
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true) // gRPC via http:// uses HTTP/2 with prior knowledge
+	return clientWithTimeouts(&http.Transport{
+		Protocols:          &protocols,
+		TLSClientConfig:    tlsClientConfig(),
+		DisableCompression: true, // gRPC uses its own message compression
+	})
+}
+
+func prependGrpcMessagePrefix(message []byte) []byte {
+	prefixed := make([]byte, grpcMessagePrefixLength, grpcMessagePrefixLength+len(message))
+	prefixed[0] = 0 // uncompressed
+	binary.BigEndian.PutUint32(prefixed[1:], uint32(len(message)))
+	return append(prefixed, message...)
+}
+
+func extractGrpcMessage(body []byte, encoding string) []byte {
+	if len(body) < grpcMessagePrefixLength {
+		PanicWithMessage(fmt.Sprintf("Received gRPC response body of %d bytes which is too short to contain a length-prefixed message.", len(body)))
+	}
+
+	compressed := body[0] == 1
+	length := binary.BigEndian.Uint32(body[1:grpcMessagePrefixLength])
+	message := body[grpcMessagePrefixLength:]
+
+	if uint32(len(message)) != length {
+		PanicWithMessage(fmt.Sprintf("Expected a single gRPC message of %d bytes, but the response body contains %d bytes after the prefix.", length, len(message)))
+	}
+
+	if !compressed {
+		return message
+	}
+
+	if encoding != "gzip" {
+		PanicWithMessage("Received gRPC message compressed with unsupported encoding '" + encoding + "'. Only gzip is supported.")
+	}
+	gzipReader, err := gzip.NewReader(bytes.NewReader(message))
+	PanicOnError(err)
+	decompressed, err := io.ReadAll(gzipReader)
+	PanicOnError(err)
+	return decompressed
+}
+
+func ensureGrpcStatusIsOk(httpResponse *http.Response) {
+	// Trailers-only responses carry the status in the headers instead.
+	status := httpResponse.Trailer.Get("Grpc-Status")
+	message := httpResponse.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status = httpResponse.Header.Get("Grpc-Status")
+		message = httpResponse.Header.Get("Grpc-Message")
+	}
+
+	if status == "" {
+		PanicWithMessage("Received gRPC response without grpc-status. Is " + CurrentConfig.Url + " a gRPC server?")
+	}
+
+	code, err := strconv.Atoi(status)
+	PanicWithMessageOnError(err, func() string { return "Received invalid grpc-status: " + status })
+
+	if code == 0 {
+		return
+	}
+
+	decodedMessage, err := url.PathUnescape(message)
+	if err != nil {
+		decodedMessage = message
+	}
+
+	PanicWithMessage(fmt.Sprintf("gRPC request was unsuccessful. Received status %s (%d): %s", grpcStatusCodeName(code), code, decodedMessage))
+}
+
+func grpcStatusCodeName(code int) string {
+	if code < 0 || code >= len(grpcStatusCodeNames) {
+		return "UNKNOWN"
+	}
+	return grpcStatusCodeNames[code]
+}
+
+func formatTrailers(trailers http.Header) string {
+	var names []string
+	for name := range trailers {
+		names = append(names, name)
+	}
+	sort.Strings(names) // deterministic output for testing
+
+	var lines []string
+	for _, name := range names {
+		for _, value := range trailers[name] {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
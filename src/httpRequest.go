@@ -3,7 +3,8 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"errors"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,7 +12,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/kballard/go-shellquote"
@@ -24,41 +26,79 @@ func invokeInternalHttpRequest(requestBinary []byte) ([]byte, string) {
 		fmt.Println("Invoking internal http request.")
 	}
 
-	if usingUnsupportedNonDefaultHeaders() {
-		PanicDueToUnsupportedHeadersWhenInternalHttp(CurrentConfig.RequestHeaders)
-	}
+	client := internalHttpClient()
 
-	var httpResponse *http.Response
-	var err error
-	switch CurrentConfig.Method {
-	case "GET":
-		if CurrentConfig.RequestType != "" {
-			PanicWithMessage("Internal Http implementation doesn't support GET requests with body. Please use curl.")
+	return invokeWithRetries(func() ([]byte, string, error) {
+		httpResponse, err := client.Do(newInternalHttpRequest(requestBinary))
+		if err != nil {
+			return nil, "", err
 		}
-		httpResponse, err = http.Get(CurrentConfig.Url)
-	case "POST":
-		httpResponse, err = http.Post(CurrentConfig.Url, DefaultContentType, bytes.NewReader(requestBinary))
-	default:
-		PanicWithMessage("HTTP method " + CurrentConfig.Method + " not supported with internal HTTP implementation. Please use curl.")
-	}
+		defer func() { _ = httpResponse.Body.Close() }()
 
-	PanicWithMessageOnError(err, func() string { return "Failed internal HTTP request. Error: " + err.Error() })
-	defer func() { _ = httpResponse.Body.Close() }()
+		body, err := io.ReadAll(httpResponse.Body)
+		if err != nil {
+			return nil, "", err
+		}
 
-	body, err := io.ReadAll(httpResponse.Body)
-	PanicOnError(err)
+		headers, err := httputil.DumpResponse(httpResponse, false)
+		return body, strings.TrimSpace(string(headers)), nil
+	}, internalRequestErrorKind, "Failed internal HTTP request.")
+}
 
-	headers, err := httputil.DumpResponse(httpResponse, false)
-	headersString := string(headers)
+func newInternalHttpRequest(requestBinary []byte) *http.Request {
+	// Similar to curl, the body is only sent, if a request type was provided.
+	var requestBody io.Reader = http.NoBody
+	if CurrentConfig.RequestType != "" {
+		requestBody = bytes.NewReader(requestBinary)
+	}
 
-	ensureStatusCodeIs2XX(headersString)
+	httpRequest, err := http.NewRequest(CurrentConfig.Method, CurrentConfig.Url, requestBody)
+	PanicWithMessageOnError(err, func() string { return "Could not create internal HTTP request. Error: " + err.Error() })
+	addRequestHeaders(httpRequest)
+	return httpRequest
+}
 
-	return body, strings.TrimSpace(headersString)
+// Adds the headers given via -H and the default headers. The header Host determines the host of the request instead.
+func addRequestHeaders(httpRequest *http.Request) {
+	for _, header := range CurrentConfig.RequestHeaders {
+		name, value := splitHeader(header)
+		if strings.EqualFold(name, "Host") {
+			httpRequest.Host = value
+		} else {
+			httpRequest.Header.Add(name, value)
+		}
+	}
 }
 
-func usingUnsupportedNonDefaultHeaders() bool {
-	usingNonDefaultHeaders := len(CurrentConfig.RequestHeaders) != 1 || CurrentConfig.RequestHeaders[0] != DefaultHeaders[0]
-	return CurrentConfig.NoDefaultHeaders || usingNonDefaultHeaders
+func internalHttpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsClientConfig()
+	return clientWithTimeouts(transport)
+}
+
+// Creates the TLS configuration of the internal http implementation from --cacert, --cert, --key, --insecure and --tls-server-name.
+func tlsClientConfig() *tls.Config {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: CurrentConfig.InsecureSkipVerify,
+		ServerName:         CurrentConfig.TlsServerName,
+	}
+
+	if CurrentConfig.CaCertFile != "" {
+		caCerts, err := os.ReadFile(CurrentConfig.CaCertFile)
+		PanicWithMessageOnError(err, func() string { return "Could not read the CA certificates. Error: " + err.Error() })
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCerts) {
+			PanicWithMessage("No PEM encoded certificates found in " + CurrentConfig.CaCertFile + ".")
+		}
+	}
+
+	if CurrentConfig.ClientCertFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(CurrentConfig.ClientCertFile, CurrentConfig.ClientKeyFile)
+		PanicWithMessageOnError(err, func() string { return "Could not load the client certificate and key. Error: " + err.Error() })
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig
 }
 
 func invokeCurlRequest(requestBinary []byte, curlPath string) ([]byte, string) {
@@ -93,6 +133,26 @@ func invokeCurlRequest(requestBinary []byte, curlPath string) ([]byte, string) {
 		curlArgs = append(curlArgs, "-H", header)
 	}
 
+	if CurrentConfig.CaCertFile != "" {
+		curlArgs = append(curlArgs, "--cacert", CurrentConfig.CaCertFile)
+	}
+
+	if CurrentConfig.ClientCertFile != "" {
+		curlArgs = append(curlArgs, "--cert", CurrentConfig.ClientCertFile, "--key", CurrentConfig.ClientKeyFile)
+	}
+
+	if CurrentConfig.InsecureSkipVerify {
+		curlArgs = append(curlArgs, "--insecure")
+	}
+
+	if CurrentConfig.ConnectTimeoutSeconds != 0 {
+		curlArgs = append(curlArgs, "--connect-timeout", formatSeconds(CurrentConfig.ConnectTimeoutSeconds))
+	}
+
+	if CurrentConfig.MaxTimeSeconds != 0 {
+		curlArgs = append(curlArgs, "--max-time", formatSeconds(CurrentConfig.MaxTimeSeconds))
+	}
+
 	individualAdditionalCurlArgs, err := shellquote.Split(CurrentConfig.AdditionalCurlArgs)
 	PanicOnError(err)
 	if CurrentConfig.Verbose {
@@ -106,43 +166,70 @@ func invokeCurlRequest(requestBinary []byte, curlPath string) ([]byte, string) {
 		fmt.Printf("Total curl args:\n  %s\n", strings.Join(curlArgs[1:], "\n  "))
 	}
 
-	curlStdOut := bytes.NewBuffer([]byte{})
-	curlStdErr := bytes.NewBuffer([]byte{})
-	curlCmd := exec.Cmd{
-		Path:   curlPath,
-		Args:   curlArgs,
-		Stdout: bufio.NewWriter(curlStdOut),
-		Stderr: bufio.NewWriter(curlStdErr),
-	}
+	return invokeWithRetries(func() ([]byte, string, error) {
+		// files of previous attempts must not be mistaken for the response of this attempt
+		_ = os.Remove(responseBinaryFile)
+		_ = os.Remove(responseHeadersTextFile)
+
+		curlStdOut := bytes.NewBuffer([]byte{})
+		curlStdErr := bytes.NewBuffer([]byte{})
+		curlCmd := exec.Cmd{
+			Path:   curlPath,
+			Args:   curlArgs,
+			Stdout: bufio.NewWriter(curlStdOut),
+			Stderr: bufio.NewWriter(curlStdErr),
+		}
 
-	err = curlCmd.Run()
+		err := curlCmd.Run()
 
-	if !CurrentConfig.ShowOutputOnly && !CurrentConfig.SilentMode && curlStdOut.Len() != 0 {
-		fmt.Printf("%s CURL Output      %s\n%s\n", VISUAL_SEPARATOR, VISUAL_SEPARATOR, curlStdOut.String())
-	}
+		if !CurrentConfig.ShowOutputOnly && !CurrentConfig.SilentMode && curlStdOut.Len() != 0 {
+			fmt.Printf("%s CURL Output      %s\n%s\n", VISUAL_SEPARATOR, VISUAL_SEPARATOR, curlStdOut.String())
+		}
 
-	if !CurrentConfig.ShowOutputOnly && curlStdErr.Len() != 0 {
-		fmt.Printf("%s CURL ERROR       %s\n%s\n", VISUAL_SEPARATOR, VISUAL_SEPARATOR, curlStdErr.String())
-	}
+		if !CurrentConfig.ShowOutputOnly && curlStdErr.Len() != 0 {
+			fmt.Printf("%s CURL ERROR       %s\n%s\n", VISUAL_SEPARATOR, VISUAL_SEPARATOR, curlStdErr.String())
+		}
+
+		if err != nil {
+			return nil, "", err
+		}
 
-	PanicWithMessageOnError(err, func() string { return "Encountered an error while running curl. Error: " + err.Error() })
+		responseBinary, err := os.ReadFile(responseBinaryFile)
+		responseHeaders, err := os.ReadFile(responseHeadersTextFile)
+		return responseBinary, strings.TrimSpace(string(responseHeaders)), nil
+	}, curlErrorKind, "Encountered an error while running curl.")
+}
 
-	responseBinary, err := os.ReadFile(responseBinaryFile)
-	responseHeaders, err := os.ReadFile(responseHeadersTextFile)
-	responseHeadersText := strings.TrimSpace(string(responseHeaders))
+// Any 2XX status code and the status codes of --accept-status are accepted - unless a specific status code is expected via --expect-status.
+func ensureStatusCodeIsAccepted(headers string) {
+	httpStatusLine := strings.Split(headers, "\n")[0]
 
-	ensureStatusCodeIs2XX(responseHeadersText)
+	if CurrentConfig.ExpectedStatusCode != 0 {
+		ensureStatusCodeIsExpected(httpStatusLine)
+		return
+	}
 
-	return responseBinary, responseHeadersText
+	if !isStatusCodeAccepted(statusCodeOfHeaders(headers)) {
+		acceptedStatusCodes := "2XX"
+		for _, statusCode := range CurrentConfig.AcceptedStatusCodes {
+			acceptedStatusCodes += ", " + strconv.Itoa(statusCode)
+		}
+		PanicDueToHttpStatus("Request was unsuccessful. Received response status code outside of " + acceptedStatusCodes + ". Got: " + httpStatusLine)
+	}
 }
 
-func ensureStatusCodeIs2XX(headers string) {
-	httpStatusLine := strings.Split(headers, "\n")[0]
-	matches, err := regexp.MatchString("HTTP/.* 2[0-9][0-9] .*", httpStatusLine)
-	AssertSuccess(err)
+func isStatusCodeAccepted(statusCode int) bool {
+	if CurrentConfig.ExpectedStatusCode != 0 {
+		return statusCode == CurrentConfig.ExpectedStatusCode
+	}
+	return (200 <= statusCode && statusCode <= 299) || slices.Contains(CurrentConfig.AcceptedStatusCodes, statusCode)
+}
 
-	if !matches {
-		err := errors.New("Request was unsuccessful. Received response status code outside of 2XX. Got: " + httpStatusLine)
-		PanicOnError(err)
+// Splits a header of the form 'Name: value' into its name and value.
+func splitHeader(header string) (string, string) {
+	name, value, found := strings.Cut(header, ":")
+	if !found {
+		PanicWithMessage("Header " + header + " is not of the form 'Name: value'.")
 	}
+	return strings.TrimSpace(name), strings.TrimSpace(value)
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/reporter"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+/*
+As an alternative to protoc, the .proto files can be compiled in-process via protocompile.
+It produces the same FileDescriptorSet (including all imports) as
+`protoc --include_imports -o/out.bin -I /proto new-file.proto` would - hence the remaining
+workflow is identical for both approaches.
+
+The well-known Google Protobuf files are embedded in protocompile. Hence, neither protoc
+nor the protocurl-internal directory is needed in this mode.
+
+See:
+	https://pkg.go.dev/github.com/bufbuild/protocompile
+*/
+
+func compileProtoFilesInternallyToFileDescriptorSet() *descriptorpb.FileDescriptorSet {
+	if CurrentConfig.Verbose {
+		fmt.Println("Compiling .proto files via internal compiler instead of protoc.")
+	}
+
+	protoFiles := collectRelevantProtoFiles()
+	for i := range protoFiles {
+		protoFiles[i] = filepath.ToSlash(protoFiles[i]) // protocompile expects import-style paths
+	}
+
+	sourceInfoMode := protocompile.SourceInfoNone
+	if protoSourceInfoIncluded {
+		sourceInfoMode = protocompile.SourceInfoStandard
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{CurrentConfig.ProtoFilesDir},
+		}),
+		Reporter: reporter.NewReporter(nil, func(err reporter.ErrorWithPos) {
+			_, _ = fmt.Fprintln(os.Stderr, "Encountered warnings while attempting to convert input .proto to FileDescriptorSet via internal compiler:\n"+err.Error())
+		}),
+		SourceInfoMode: sourceInfoMode,
+	}
+
+	compiledFiles, err := compiler.Compile(context.Background(), protoFiles...)
+	PanicWithMessageOnError(err, func() string {
+		return "Failed to convert input .proto to FileDescriptorSet via internal compiler."
+	})
+
+	protoFileDescriptorSet := descriptorpb.FileDescriptorSet{}
+	alreadyAdded := make(map[string]bool)
+	for _, file := range compiledFiles {
+		appendFileWithImportsInTopologicalOrder(file, &protoFileDescriptorSet, alreadyAdded)
+	}
+
+	return &protoFileDescriptorSet
+}
+
+// Equivalent to protoc's --include_imports: Every file is preceded by its (transitive) dependencies.
+func appendFileWithImportsInTopologicalOrder(file protoreflect.FileDescriptor, set *descriptorpb.FileDescriptorSet, alreadyAdded map[string]bool) {
+	if alreadyAdded[file.Path()] {
+		return
+	}
+	alreadyAdded[file.Path()] = true
+
+	imports := file.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		appendFileWithImportsInTopologicalOrder(imports.Get(i).FileDescriptor, set, alreadyAdded)
+	}
+
+	set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+}
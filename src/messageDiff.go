@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+/*
+Compares two messages of the same type field by field. In contrast to comparing their text formats,
+the order of fields and unset fields with default values do not produce differences.
+
+Each difference is reported with the path of the field, e.g. misc[1].weatherOfPastFewDays[0] or labels[key].
+Ignored fields are given by their path without indices or keys, e.g. misc.weatherOfPastFewDays.
+
+See:
+	https://pkg.go.dev/google.golang.org/protobuf/reflect/protoreflect#Message
+*/
+
+const unsetFieldValue = "<unset>"
+
+type MessageDifference struct {
+	Path  string
+	Left  string
+	Right string
+}
+
+type MessageDiffer struct {
+	OnlyFieldsSetInLeft bool     // fields only set in the right message are not compared
+	UnorderedLists      bool     // repeated fields are compared as multisets
+	IgnoredFields       []string // paths without indices or keys
+	differences         []MessageDifference
+}
+
+func (differ *MessageDiffer) Compare(left protoreflect.Message, right protoreflect.Message) []MessageDifference {
+	differ.differences = nil
+	differ.compareMessages("", "", left, right)
+	return differ.differences
+}
+
+// The fieldPath of the messages is kept, so that ignored fields within them are matched.
+func (differ *MessageDiffer) isEqual(fieldPath string, left protoreflect.Message, right protoreflect.Message) bool {
+	nested := *differ
+	nested.differences = nil
+	nested.compareMessages("", fieldPath, left, right)
+	return len(nested.differences) == 0
+}
+
+func (differ *MessageDiffer) isIgnored(fieldPath string) bool {
+	for _, ignored := range differ.IgnoredFields {
+		if fieldPath == ignored || strings.HasPrefix(fieldPath, ignored+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (differ *MessageDiffer) report(path string, left string, right string) {
+	differ.differences = append(differ.differences, MessageDifference{Path: path, Left: left, Right: right})
+}
+
+// path contains indices and keys for the output whereas fieldPath does not and is used to match ignored fields.
+func (differ *MessageDiffer) compareMessages(path string, fieldPath string, left protoreflect.Message, right protoreflect.Message) {
+	fields := left.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !left.Has(field) && (differ.OnlyFieldsSetInLeft || !right.Has(field)) {
+			continue
+		}
+
+		nestedPath := joinFieldPath(path, string(field.Name()))
+		nestedFieldPath := joinFieldPath(fieldPath, string(field.Name()))
+		if differ.isIgnored(nestedFieldPath) {
+			continue
+		}
+
+		switch {
+		case field.IsList():
+			differ.compareLists(nestedPath, nestedFieldPath, field, left.Get(field).List(), right.Get(field).List())
+		case field.IsMap():
+			differ.compareMaps(nestedPath, nestedFieldPath, field, left.Get(field).Map(), right.Get(field).Map())
+		case field.Message() != nil && left.Has(field) && right.Has(field):
+			differ.compareMessages(nestedPath, nestedFieldPath, left.Get(field).Message(), right.Get(field).Message())
+		case left.Has(field) != right.Has(field) || !left.Get(field).Equal(right.Get(field)):
+			differ.report(nestedPath, formatFieldValueOrUnset(left, field), formatFieldValueOrUnset(right, field))
+		}
+	}
+
+	if !differ.OnlyFieldsSetInLeft && string(left.GetUnknown()) != string(right.GetUnknown()) {
+		differ.report(joinFieldPath(path, "<unknown fields>"), fmt.Sprintf("%d bytes", len(left.GetUnknown())), fmt.Sprintf("%d bytes", len(right.GetUnknown())))
+	}
+}
+
+func (differ *MessageDiffer) compareLists(path string, fieldPath string, field protoreflect.FieldDescriptor, left protoreflect.List, right protoreflect.List) {
+	if differ.UnorderedLists {
+		differ.compareListsUnordered(path, fieldPath, field, left, right)
+		return
+	}
+
+	if left.Len() != right.Len() {
+		differ.report(path, formatElementCount(left.Len()), formatElementCount(right.Len()))
+		return
+	}
+
+	for i := 0; i < left.Len(); i++ {
+		elementPath := fmt.Sprintf("%s[%d]", path, i)
+		if field.Message() != nil {
+			differ.compareMessages(elementPath, fieldPath, left.Get(i).Message(), right.Get(i).Message())
+		} else if !left.Get(i).Equal(right.Get(i)) {
+			differ.report(elementPath, formatFieldValue(field, left.Get(i)), formatFieldValue(field, right.Get(i)))
+		}
+	}
+}
+
+// Each element of the left list is matched with an equal and not yet matched element of the right list.
+// The remaining elements of both lists are reported.
+func (differ *MessageDiffer) compareListsUnordered(path string, fieldPath string, field protoreflect.FieldDescriptor, left protoreflect.List, right protoreflect.List) {
+	matchedRight := make([]bool, right.Len())
+	for i := 0; i < left.Len(); i++ {
+		matched := false
+		for j := 0; j < right.Len() && !matched; j++ {
+			if matchedRight[j] {
+				continue
+			}
+			if field.Message() != nil {
+				matched = differ.isEqual(fieldPath, left.Get(i).Message(), right.Get(j).Message())
+			} else {
+				matched = left.Get(i).Equal(right.Get(j))
+			}
+			matchedRight[j] = matched
+		}
+		if !matched {
+			differ.report(fmt.Sprintf("%s[%d]", path, i), formatFieldValue(field, left.Get(i)), unsetFieldValue)
+		}
+	}
+
+	if differ.OnlyFieldsSetInLeft {
+		return
+	}
+	for j := 0; j < right.Len(); j++ {
+		if !matchedRight[j] {
+			differ.report(fmt.Sprintf("%s[%d]", path, j), unsetFieldValue, formatFieldValue(field, right.Get(j)))
+		}
+	}
+}
+
+func (differ *MessageDiffer) compareMaps(path string, fieldPath string, field protoreflect.FieldDescriptor, left protoreflect.Map, right protoreflect.Map) {
+	var keys []protoreflect.MapKey
+	left.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if !differ.OnlyFieldsSetInLeft {
+		right.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+			if !left.Has(key) {
+				keys = append(keys, key)
+			}
+			return true
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() }) // deterministic output for testing
+
+	valueField := field.MapValue()
+	for _, key := range keys {
+		entryPath := fmt.Sprintf("%s[%s]", path, key.String())
+		switch {
+		case !right.Has(key):
+			differ.report(entryPath, formatFieldValue(valueField, left.Get(key)), unsetFieldValue)
+		case !left.Has(key):
+			differ.report(entryPath, unsetFieldValue, formatFieldValue(valueField, right.Get(key)))
+		case valueField.Message() != nil:
+			differ.compareMessages(entryPath, fieldPath, left.Get(key).Message(), right.Get(key).Message())
+		case !left.Get(key).Equal(right.Get(key)):
+			differ.report(entryPath, formatFieldValue(valueField, left.Get(key)), formatFieldValue(valueField, right.Get(key)))
+		}
+	}
+}
+
+func formatElementCount(count int) string {
+	if count == 1 {
+		return "1 element"
+	}
+	return fmt.Sprintf("%d elements", count)
+}
+
+func joinFieldPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func formatFieldValueOrUnset(msg protoreflect.Message, field protoreflect.FieldDescriptor) string {
+	if !msg.Has(field) {
+		return unsetFieldValue
+	}
+	return formatFieldValue(field, msg.Get(field))
+}
+
+func formatFieldValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch {
+	case field.Message() != nil:
+		return formatMessageInSingleLine(value.Message())
+	case field.Enum() != nil:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.Itoa(int(value.Enum()))
+	case field.Kind() == protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case field.Kind() == protoreflect.BytesKind:
+		return strconv.Quote(string(value.Bytes()))
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+// Formats the message similar to the text format in a single line. In contrast to prototext, the output is stable.
+func formatMessageInSingleLine(msg protoreflect.Message) string {
+	var formattedFields []string
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !msg.Has(field) {
+			continue
+		}
+
+		var formattedValues []string
+		switch {
+		case field.IsList():
+			list := msg.Get(field).List()
+			for j := 0; j < list.Len(); j++ {
+				formattedValues = append(formattedValues, formatFieldValue(field, list.Get(j)))
+			}
+			formattedFields = append(formattedFields, string(field.Name())+": ["+strings.Join(formattedValues, ", ")+"]")
+		case field.IsMap():
+			msg.Get(field).Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				formattedValues = append(formattedValues, key.String()+": "+formatFieldValue(field.MapValue(), value))
+				return true
+			})
+			sort.Strings(formattedValues)
+			formattedFields = append(formattedFields, string(field.Name())+": {"+strings.Join(formattedValues, ", ")+"}")
+		default:
+			formattedFields = append(formattedFields, string(field.Name())+": "+formatFieldValue(field, msg.Get(field)))
+		}
+	}
+	return "{" + strings.Join(formattedFields, ", ") + "}"
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+When the type of a payload is unknown, every message type of the registry is scored against the raw payload
+via --suggest-types. The payload is parsed as in the raw decoding (see rawDecoding.go) and each of its fields
+scores a point for an existing field number, a matching wire type and valid UTF-8 for string fields.
+Embedded messages are scored recursively against the message type of their field.
+A message type which decodes the payload strictly, i.e. without unknown fields, scores an additional point.
+*/
+
+const suggestedMessageTypesCount = 5
+
+type messageTypeSuggestion struct {
+	fullName protoreflect.FullName
+	score    int
+	strict   bool
+}
+
+func suggestMessageTypes(binary []byte, registry *protoregistry.Files) []messageTypeSuggestion {
+	fields, err := parseRawFields(binary)
+	PanicWithMessageOnError(err, func() string {
+		return "Could not suggest message types, as the payload is not a valid Protobuf message."
+	})
+
+	var suggestions []messageTypeSuggestion
+	for _, descriptor := range collectAllMessageDescriptors(registry) {
+		if descriptor.IsMapEntry() {
+			continue
+		}
+		suggestion := messageTypeSuggestion{fullName: descriptor.FullName(), score: scoreRawFields(fields, descriptor)}
+		if decodesStrictly(binary, descriptor) {
+			suggestion.strict = true
+			suggestion.score++
+		}
+		if suggestion.score > 0 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	// the message types are already sorted by their full names
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].score > suggestions[j].score })
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Scored %d message types with matching fields.\n", len(suggestions))
+	}
+
+	return suggestions[:min(len(suggestions), suggestedMessageTypesCount)]
+}
+
+func scoreRawFields(fields []rawField, descriptor protoreflect.MessageDescriptor) int {
+	score := 0
+	for _, field := range fields {
+		fieldDescriptor := descriptor.Fields().ByNumber(field.number)
+		if fieldDescriptor == nil {
+			continue
+		}
+		score++
+
+		if !wireTypeMatches(fieldDescriptor, field.wireType) {
+			continue
+		}
+		score++
+
+		switch fieldDescriptor.Kind() {
+		case protoreflect.StringKind:
+			if utf8.Valid(field.bytes) {
+				score++
+			}
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			embedded := field.message
+			if embedded == nil { // printable text is not parsed as an embedded message during raw decoding
+				embedded, _ = parseRawFields(field.bytes)
+			}
+			score += scoreRawFields(embedded, fieldDescriptor.Message())
+		}
+	}
+	return score
+}
+
+// Repeated numeric fields can also be packed into a single length-delimited field.
+func wireTypeMatches(field protoreflect.FieldDescriptor, wireType protowire.Type) bool {
+	var expected protowire.Type
+	switch field.Kind() {
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind:
+		return wireType == protowire.BytesType
+	case protoreflect.GroupKind:
+		return wireType == protowire.StartGroupType
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		expected = protowire.Fixed32Type
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		expected = protowire.Fixed64Type
+	default:
+		expected = protowire.VarintType
+	}
+	return wireType == expected || (field.IsList() && wireType == protowire.BytesType)
+}
+
+func decodesStrictly(binary []byte, descriptor protoreflect.MessageDescriptor) bool {
+	msg := dynamicpb.NewMessage(descriptor)
+	return proto.Unmarshal(binary, msg) == nil && !hasUnknownFields(msg)
+}
+
+func hasUnknownFields(msg protoreflect.Message) bool {
+	if len(msg.GetUnknown()) != 0 {
+		return true
+	}
+
+	unknown := false
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case field.IsMap():
+			if field.MapValue().Message() != nil {
+				value.Map().Range(func(_ protoreflect.MapKey, entry protoreflect.Value) bool {
+					unknown = hasUnknownFields(entry.Message())
+					return !unknown
+				})
+			}
+		case field.IsList() && field.Message() != nil:
+			for i := 0; i < value.List().Len() && !unknown; i++ {
+				unknown = hasUnknownFields(value.List().Get(i).Message())
+			}
+		case field.Message() != nil:
+			unknown = hasUnknownFields(value.Message())
+		}
+		return !unknown
+	})
+	return unknown
+}
+
+func displayMessageTypeSuggestions(title string, binary []byte, registry *protoregistry.Files) {
+	suggestions := suggestMessageTypes(binary, registry)
+
+	if !CurrentConfig.ShowOutputOnly && !CurrentConfig.SilentMode {
+		fmt.Printf("%s %s %s %s\n", VISUAL_SEPARATOR, title, VISUAL_SEPARATOR, RECV)
+	}
+	if CurrentConfig.SilentMode {
+		return
+	}
+
+	if len(suggestions) == 0 {
+		fmt.Println("No message type has any matching fields.")
+		return
+	}
+	var lines []string
+	for _, suggestion := range suggestions {
+		line := fmt.Sprintf("%s: score %d", suggestion.fullName, suggestion.score)
+		if suggestion.strict {
+			line += " (decodes without unknown fields)"
+		}
+		lines = append(lines, line)
+	}
+	fmt.Println(strings.Join(lines, "\n"))
+}
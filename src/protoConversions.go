@@ -6,6 +6,7 @@ import (
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
@@ -47,12 +48,14 @@ func textToMsgAndBinary(messageType string, text string, registry *protoregistry
 	messageDescriptor := resolveMessageByName(messageType, registry)
 	msg := dynamicpb.NewMessage(*messageDescriptor)
 
+	resolver := newRegistryTypeResolver(registry)
+
 	var err error
 	switch CurrentConfig.InTextType {
 	case IText:
-		err = prototext.Unmarshal([]byte(text), msg)
+		err = prototext.UnmarshalOptions{Resolver: resolver}.Unmarshal([]byte(text), msg)
 	case IJson:
-		err = protojson.Unmarshal([]byte(text), msg)
+		err = protojson.UnmarshalOptions{Resolver: resolver}.Unmarshal([]byte(text), msg)
 	}
 	PanicOnError(err)
 
@@ -69,21 +72,37 @@ func protoBinaryToMsgAndText(messageType string, binary []byte, outFormat OutTex
 	err := proto.Unmarshal(binary, msg)
 	PanicOnError(err)
 
+	var text string
+	if messageType == WellKnownEmptyMessageType {
+		text, err = rawBinaryToText(binary, outFormat) // all fields are unknown. See rawDecoding.go
+	} else {
+		text, err = msgToText(msg, outFormat, newRegistryTypeResolver(registry))
+	}
+	PanicOnError(err)
+
+	return text, msg
+}
+
+// The resolver is used to expand google.protobuf.Any messages into their structured contents.
+func msgToText(msg proto.Message, outFormat OutTextType, resolver *registryTypeResolver) (string, error) {
 	var textBytes = []byte{}
+	var err error
 	switch outFormat {
 	case OText:
-		textBytes, err = formatUnknownFieldsIfApplicable(textFormatOptions).Marshal(msg)
+		textOpts := formatUnknownFieldsIfApplicable(textFormatOptions)
+		textOpts.Resolver = resolver
+		textBytes, err = textOpts.Marshal(msg)
 	case OJsonDense:
-		textBytes, err = jsonDenseformatOptions.Marshal(msg)
+		jsonOpts := jsonDenseformatOptions // shallow copy
+		jsonOpts.Resolver = resolver
+		textBytes, err = jsonOpts.Marshal(msg)
 	case OJsonPretty:
-		textBytes, err = jsonPrettyformatOptions.Marshal(msg)
+		jsonOpts := jsonPrettyformatOptions // shallow copy
+		jsonOpts.Resolver = resolver
+		textBytes, err = jsonOpts.Marshal(msg)
 	}
-	PanicOnError(err)
 
-	text := string(textBytes)
-	text = strings.TrimSuffix(text, "\n")
-
-	return text, msg
+	return strings.TrimSuffix(string(textBytes), "\n"), err
 }
 
 func formatUnknownFieldsIfApplicable(opts prototext.MarshalOptions) prototext.MarshalOptions {
@@ -91,3 +110,41 @@ func formatUnknownFieldsIfApplicable(opts prototext.MarshalOptions) prototext.Ma
 	newOpts.EmitUnknown = true
 	return newOpts
 }
+
+// Resolves the types of the loaded .proto files first and the types compiled into protocurl afterwards.
+// Without it, only the types compiled into protocurl could be used for google.protobuf.Any messages.
+type registryTypeResolver struct {
+	registryTypes *dynamicpb.Types
+}
+
+func newRegistryTypeResolver(registry *protoregistry.Files) *registryTypeResolver {
+	return &registryTypeResolver{registryTypes: dynamicpb.NewTypes(registry)}
+}
+
+func (r *registryTypeResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if messageType, err := r.registryTypes.FindMessageByName(name); err == nil {
+		return messageType, nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (r *registryTypeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if messageType, err := r.registryTypes.FindMessageByURL(url); err == nil {
+		return messageType, nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByURL(url)
+}
+
+func (r *registryTypeResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if extensionType, err := r.registryTypes.FindExtensionByName(field); err == nil {
+		return extensionType, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r *registryTypeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if extensionType, err := r.registryTypes.FindExtensionByNumber(message, field); err == nil {
+		return extensionType, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+The list and describe subcommands show the contents of the compiled .proto files, so that the request and
+response types do not need to be looked up in the .proto files by hand.
+
+list shows the full names of all messages, enums and services. describe shows the definition of a single
+message, enum, service or method similar to the .proto syntax - including the comments, if the .proto files
+were compiled with them. Precompiled FileDescriptorSets only contain comments, if they were created via
+'protoc --include_source_info ...'.
+*/
+
+var listMessages bool
+var listEnums bool
+var listServices bool
+
+var listCmd = &cobra.Command{
+	Short: "Lists the messages, enums and services of the .proto files.",
+	Use: "list [flags] [filter]\n\n" +
+		"Shows one line 'kind full-name' per type, e.g. 'message happyday.HappyDayRequest'. Nested messages and enums are included.\n" +
+		"If a filter is provided, then only the types whose full names contain it are listed.\n" +
+		"If none of --messages, --enums and --services is provided, then all kinds are listed.",
+	Example:               "  protocurl list -I my-protos --messages mypackage",
+	Args:                  cobra.MaximumNArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		propagateProtoFileFlags()
+		registry := convertProtoFilesToProtoRegistryFiles()
+
+		filter := ""
+		if len(args) == 1 {
+			filter = args[0]
+		}
+
+		listAll := !listMessages && !listEnums && !listServices
+		var lines []string
+		appendMatching := func(kind string, fullName protoreflect.FullName) {
+			if strings.Contains(string(fullName), filter) {
+				lines = append(lines, kind+" "+string(fullName))
+			}
+		}
+
+		if listAll || listMessages {
+			for _, message := range collectAllMessageDescriptors(registry) {
+				if !message.IsMapEntry() {
+					appendMatching("message", message.FullName())
+				}
+			}
+		}
+		if listAll || listEnums {
+			for _, enum := range collectAllEnumDescriptors(registry) {
+				appendMatching("enum", enum.FullName())
+			}
+		}
+		if listAll || listServices {
+			for _, service := range collectAllServiceDescriptors(registry) {
+				appendMatching("service", service.FullName())
+			}
+		}
+
+		if len(lines) == 0 {
+			PanicWithMessage("No types found. Check the folder of proto files (-I), the filter and verbose (-v).")
+		}
+		fmt.Println(strings.Join(lines, "\n"))
+	},
+}
+
+var describeCmd = &cobra.Command{
+	Short: "Describes a message, enum, service or method of the .proto files.",
+	Use: "describe [flags] name\n\n" +
+		"Shows the definition of the type with the given name similar to the .proto syntax. This includes the fields with their numbers, types,\n" +
+		"oneofs, options and comments. The name is a full package path (e.g. mypackage.MyMessage or mypackage.MyService/MyMethod)\n" +
+		"or can be shortened to '..', if the name is unique. E.g. ..MyMessage",
+	Example:               "  protocurl describe -I my-protos ..MyRequest",
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		propagateProtoFileFlags()
+		protoSourceInfoIncluded = true
+		registry := convertProtoFilesToProtoRegistryFiles()
+
+		descriptor := resolveDescriptorByName(args[0], registry)
+		describer := protoDescriber{registry: registry}
+
+		kind := descriptorKind(descriptor)
+		article := "a"
+		if kind == "enum" {
+			article = "an"
+		}
+		fmt.Printf("%s is %s %s in %s:\n", descriptor.FullName(), article, kind, descriptor.ParentFile().Path())
+		describer.describe(descriptor, "")
+		fmt.Print(describer.text.String())
+	},
+}
+
+func initialiseDescriptionCommands() {
+	for _, command := range []*cobra.Command{listCmd, describeCmd} {
+		flags := command.Flags()
+		addProtoFileFlags(flags)
+		addVerboseFlag(flags)
+
+		rootCmd.AddCommand(command)
+	}
+
+	listCmd.Flags().BoolVar(&listMessages, "messages", false, "Lists the messages.")
+	listCmd.Flags().BoolVar(&listEnums, "enums", false, "Lists the enums.")
+	listCmd.Flags().BoolVar(&listServices, "services", false, "Lists the services.")
+}
+
+// Returns all enums (including the ones nested in messages) in the registry sorted by their full names.
+func collectAllEnumDescriptors(registry *protoregistry.Files) (enums []protoreflect.EnumDescriptor) {
+	appendAll := func(descriptors protoreflect.EnumDescriptors) {
+		for i := 0; i < descriptors.Len(); i++ {
+			enums = append(enums, descriptors.Get(i))
+		}
+	}
+
+	registry.RangeFiles(func(fileDesc protoreflect.FileDescriptor) bool {
+		appendAll(fileDesc.Enums())
+		return true
+	})
+	for _, message := range collectAllMessageDescriptors(registry) {
+		appendAll(message.Enums())
+	}
+
+	sort.Slice(enums, func(i, j int) bool { return enums[i].FullName() < enums[j].FullName() })
+	return
+}
+
+// Returns all services in the registry sorted by their full names.
+func collectAllServiceDescriptors(registry *protoregistry.Files) (services []protoreflect.ServiceDescriptor) {
+	registry.RangeFiles(func(fileDesc protoreflect.FileDescriptor) bool {
+		for i := 0; i < fileDesc.Services().Len(); i++ {
+			services = append(services, fileDesc.Services().Get(i))
+		}
+		return true
+	})
+
+	sort.Slice(services, func(i, j int) bool { return services[i].FullName() < services[j].FullName() })
+	return
+}
+
+// Resolves a message, enum, service or method given by its full name, package.Service/Method or ..Name (unique base name).
+func resolveDescriptorByName(name string, registry *protoregistry.Files) protoreflect.Descriptor {
+	if !strings.HasPrefix(name, inferredMessagePathPrefix) {
+		if CurrentConfig.Verbose {
+			fmt.Printf("Looking up type with full name: %s\n", name)
+		}
+		descriptor, err := registry.FindDescriptorByName(protoreflect.FullName(strings.Replace(name, "/", ".", 1)))
+		PanicWithMessageOnError(err, func() string {
+			return "I couldn't find any message, enum, service or method for " + name + ".\n" +
+				"Did you correctly -I (include) your proto files directory?\n" +
+				"Try 'protocurl list' to see all types."
+		})
+		if descriptorKind(descriptor) == "" {
+			PanicWithMessage("Resolved " + name + " to " + string(descriptor.FullName()) + " which is neither a message, enum, service nor method.")
+		}
+		return descriptor
+	}
+
+	searchedName := protoreflect.Name(strings.TrimPrefix(name, inferredMessagePathPrefix))
+	if CurrentConfig.Verbose {
+		fmt.Printf("Searching for type with base name: %s\n", searchedName)
+	}
+
+	var resolvedDescriptors []protoreflect.Descriptor
+	for _, message := range collectAllMessageDescriptors(registry) {
+		resolvedDescriptors = append(resolvedDescriptors, message)
+	}
+	for _, enum := range collectAllEnumDescriptors(registry) {
+		resolvedDescriptors = append(resolvedDescriptors, enum)
+	}
+	for _, service := range collectAllServiceDescriptors(registry) {
+		resolvedDescriptors = append(resolvedDescriptors, service)
+	}
+	for _, method := range collectAllMethodDescriptors(registry) {
+		resolvedDescriptors = append(resolvedDescriptors, method)
+	}
+
+	var matchingDescriptors []protoreflect.Descriptor
+	var matchingFullNames []string
+	for _, descriptor := range resolvedDescriptors {
+		if descriptor.Name() == searchedName {
+			matchingDescriptors = append(matchingDescriptors, descriptor)
+			matchingFullNames = append(matchingFullNames, string(descriptor.FullName()))
+		}
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Resolved type package-paths for name %s: %v\n", searchedName, matchingFullNames)
+	}
+
+	switch len(matchingDescriptors) {
+	case 0:
+		PanicWithMessage("No type found with base name: " + string(searchedName) + ". Check the folder of proto files (-I) and verbose (-v).")
+	case 1: /* do-nothing */
+	default:
+		PanicWithMessage(fmt.Sprintf("Type with base name is not unique. Found %d types with package paths: %v\n"+
+			"Try -v verbose or specify the type via its full package path.", len(matchingFullNames), matchingFullNames))
+	}
+
+	return matchingDescriptors[0]
+}
+
+func descriptorKind(descriptor protoreflect.Descriptor) string {
+	switch descriptor.(type) {
+	case protoreflect.MessageDescriptor:
+		return "message"
+	case protoreflect.EnumDescriptor:
+		return "enum"
+	case protoreflect.ServiceDescriptor:
+		return "service"
+	case protoreflect.MethodDescriptor:
+		return "method"
+	default:
+		return ""
+	}
+}
+
+type protoDescriber struct {
+	registry *protoregistry.Files
+	text     strings.Builder
+}
+
+func (d *protoDescriber) describe(descriptor protoreflect.Descriptor, indent string) {
+	switch descriptor := descriptor.(type) {
+	case protoreflect.MessageDescriptor:
+		d.describeMessage(descriptor, indent)
+	case protoreflect.EnumDescriptor:
+		d.describeEnum(descriptor, indent)
+	case protoreflect.ServiceDescriptor:
+		d.describeService(descriptor, indent)
+	case protoreflect.MethodDescriptor:
+		d.describeMethod(descriptor, indent)
+	}
+}
+
+func (d *protoDescriber) describeMessage(message protoreflect.MessageDescriptor, indent string) {
+	d.writeLeadingComments(message, indent)
+	d.writeLine(message, indent, "message "+string(message.Name())+" {")
+	inner := indent + "  "
+
+	d.writeOptionStatements(message.Options(), inner)
+
+	describedOneofs := map[protoreflect.FullName]bool{}
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		oneof := field.ContainingOneof()
+		if oneof == nil || oneof.IsSynthetic() {
+			d.describeField(field, inner)
+			continue
+		}
+		if describedOneofs[oneof.FullName()] {
+			continue
+		}
+		describedOneofs[oneof.FullName()] = true
+
+		d.writeLeadingComments(oneof, inner)
+		d.writeLine(oneof, inner, "oneof "+string(oneof.Name())+" {")
+		d.writeOptionStatements(oneof.Options(), inner+"  ")
+		for j := 0; j < oneof.Fields().Len(); j++ {
+			d.describeField(oneof.Fields().Get(j), inner+"  ")
+		}
+		d.text.WriteString(inner + "}\n")
+	}
+
+	for i := 0; i < message.Messages().Len(); i++ {
+		if nested := message.Messages().Get(i); !nested.IsMapEntry() {
+			d.describeMessage(nested, inner)
+		}
+	}
+	for i := 0; i < message.Enums().Len(); i++ {
+		d.describeEnum(message.Enums().Get(i), inner)
+	}
+
+	d.writeReservations(message.ReservedRanges(), message.ReservedNames(), inner)
+	d.text.WriteString(indent + "}\n")
+}
+
+func (d *protoDescriber) describeField(field protoreflect.FieldDescriptor, indent string) {
+	label := ""
+	switch {
+	case field.IsMap():
+	case field.IsList():
+		label = "repeated "
+	case field.Cardinality() == protoreflect.Required:
+		label = "required "
+	case field.HasOptionalKeyword():
+		label = "optional "
+	}
+
+	options := d.formatOptions(field.Options())
+	if field.HasDefault() {
+		options = append([]string{"default = " + formatOptionValue(field, field.Default())}, options...)
+	}
+	optionsText := ""
+	if len(options) != 0 {
+		optionsText = " [" + strings.Join(options, ", ") + "]"
+	}
+
+	d.writeLeadingComments(field, indent)
+	d.writeLine(field, indent, fmt.Sprintf("%s%s %s = %d%s;", label, fieldTypeName(field), field.Name(), field.Number(), optionsText))
+}
+
+func fieldTypeName(field protoreflect.FieldDescriptor) string {
+	switch {
+	case field.IsMap():
+		return "map<" + fieldTypeName(field.MapKey()) + ", " + fieldTypeName(field.MapValue()) + ">"
+	case field.Message() != nil:
+		return string(field.Message().FullName())
+	case field.Enum() != nil:
+		return string(field.Enum().FullName())
+	default:
+		return field.Kind().String()
+	}
+}
+
+func (d *protoDescriber) describeEnum(enum protoreflect.EnumDescriptor, indent string) {
+	d.writeLeadingComments(enum, indent)
+	d.writeLine(enum, indent, "enum "+string(enum.Name())+" {")
+	inner := indent + "  "
+
+	d.writeOptionStatements(enum.Options(), inner)
+
+	for i := 0; i < enum.Values().Len(); i++ {
+		value := enum.Values().Get(i)
+		optionsText := ""
+		if options := d.formatOptions(value.Options()); len(options) != 0 {
+			optionsText = " [" + strings.Join(options, ", ") + "]"
+		}
+		d.writeLeadingComments(value, inner)
+		d.writeLine(value, inner, fmt.Sprintf("%s = %d%s;", value.Name(), value.Number(), optionsText))
+	}
+
+	var reservedRanges []string
+	for i := 0; i < enum.ReservedRanges().Len(); i++ {
+		reservedRanges = append(reservedRanges, formatReservedRange(enum.ReservedRanges().Get(i)[0], enum.ReservedRanges().Get(i)[1]))
+	}
+	d.writeReservedStatements(reservedRanges, enum.ReservedNames(), inner)
+	d.text.WriteString(indent + "}\n")
+}
+
+func (d *protoDescriber) describeService(service protoreflect.ServiceDescriptor, indent string) {
+	d.writeLeadingComments(service, indent)
+	d.writeLine(service, indent, "service "+string(service.Name())+" {")
+	inner := indent + "  "
+
+	d.writeOptionStatements(service.Options(), inner)
+
+	for i := 0; i < service.Methods().Len(); i++ {
+		d.describeMethod(service.Methods().Get(i), inner)
+	}
+	d.text.WriteString(indent + "}\n")
+}
+
+func (d *protoDescriber) describeMethod(method protoreflect.MethodDescriptor, indent string) {
+	streamPrefix := func(isStreaming bool) string {
+		if isStreaming {
+			return "stream "
+		}
+		return ""
+	}
+
+	signature := fmt.Sprintf("rpc %s(%s%s) returns (%s%s)", method.Name(),
+		streamPrefix(method.IsStreamingClient()), method.Input().FullName(),
+		streamPrefix(method.IsStreamingServer()), method.Output().FullName())
+
+	d.writeLeadingComments(method, indent)
+	options := d.formatOptions(method.Options())
+	if len(options) == 0 {
+		d.writeLine(method, indent, signature+";")
+		return
+	}
+
+	d.writeLine(method, indent, signature+" {")
+	for _, option := range options {
+		d.text.WriteString(indent + "  option " + option + ";\n")
+	}
+	d.text.WriteString(indent + "}\n")
+}
+
+func (d *protoDescriber) writeReservations(ranges protoreflect.FieldRanges, names protoreflect.Names, indent string) {
+	var reservedRanges []string
+	for i := 0; i < ranges.Len(); i++ {
+		reservedRanges = append(reservedRanges, formatReservedRange(ranges.Get(i)[0], ranges.Get(i)[1]-1)) // field ranges are exclusive
+	}
+	d.writeReservedStatements(reservedRanges, names, indent)
+}
+
+func (d *protoDescriber) writeReservedStatements(ranges []string, names protoreflect.Names, indent string) {
+	if len(ranges) != 0 {
+		d.text.WriteString(indent + "reserved " + strings.Join(ranges, ", ") + ";\n")
+	}
+	if names.Len() != 0 {
+		var quotedNames []string
+		for i := 0; i < names.Len(); i++ {
+			quotedNames = append(quotedNames, strconv.Quote(string(names.Get(i))))
+		}
+		d.text.WriteString(indent + "reserved " + strings.Join(quotedNames, ", ") + ";\n")
+	}
+}
+
+func formatReservedRange[T ~int32](start T, end T) string {
+	if start == end {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d to %d", start, end)
+}
+
+func (d *protoDescriber) writeOptionStatements(options proto.Message, indent string) {
+	for _, option := range d.formatOptions(options) {
+		d.text.WriteString(indent + "option " + option + ";\n")
+	}
+}
+
+// The options are decoded with the registry, since custom options are extensions unknown to protocurl itself.
+// Options whose extensions are not found in the registry are omitted.
+func (d *protoDescriber) formatOptions(options proto.Message) []string {
+	optionsBinary, err := proto.Marshal(options)
+	PanicOnError(err)
+	if len(optionsBinary) == 0 {
+		return nil
+	}
+
+	optionsMsg := dynamicpb.NewMessage(options.ProtoReflect().Descriptor())
+	PanicOnError(proto.UnmarshalOptions{Resolver: newRegistryTypeResolver(d.registry)}.Unmarshal(optionsBinary, optionsMsg))
+
+	var formatted []string
+	for _, field := range setFieldsByNumber(optionsMsg) {
+		name := string(field.Name())
+		if field.IsExtension() {
+			name = "(" + string(field.FullName()) + ")"
+		}
+		formatted = append(formatted, name+" = "+formatOptionValue(field, optionsMsg.Get(field)))
+	}
+	return formatted
+}
+
+// Range visits the fields of dynamic messages in an undefined order.
+func setFieldsByNumber(msg protoreflect.Message) (fields []protoreflect.FieldDescriptor) {
+	msg.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, field)
+		return true
+	})
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Number() < fields[j].Number() })
+	return
+}
+
+// Formats the value similar to the Protobuf text format. Messages are formatted on a single line.
+func formatOptionValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch {
+	case field.IsList():
+		var elements []string
+		for i := 0; i < value.List().Len(); i++ {
+			elements = append(elements, formatSingularOptionValue(field, value.List().Get(i)))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case field.IsMap():
+		var entries []string
+		value.Map().Range(func(key protoreflect.MapKey, entry protoreflect.Value) bool {
+			entries = append(entries, "{ key: "+formatSingularOptionValue(field.MapKey(), key.Value())+
+				" value: "+formatSingularOptionValue(field.MapValue(), entry)+" }")
+			return true
+		})
+		sort.Strings(entries)
+		return "[" + strings.Join(entries, ", ") + "]"
+	default:
+		return formatSingularOptionValue(field, value)
+	}
+}
+
+func formatSingularOptionValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case protoreflect.BytesKind:
+		return quoteRawBytes(value.Bytes())
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return fmt.Sprint(value.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := value.Message()
+		var fields []string
+		for _, nestedField := range setFieldsByNumber(msg) {
+			fields = append(fields, string(nestedField.Name())+": "+formatOptionValue(nestedField, msg.Get(nestedField)))
+		}
+		if len(fields) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(fields, " ") + " }"
+	default:
+		return value.String()
+	}
+}
+
+func (d *protoDescriber) writeLeadingComments(descriptor protoreflect.Descriptor, indent string) {
+	location := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor)
+	d.writeComments(location.LeadingComments, indent)
+}
+
+// Writes the line of the descriptor with its trailing comments.
+func (d *protoDescriber) writeLine(descriptor protoreflect.Descriptor, indent string, line string) {
+	location := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor)
+	trailing := strings.TrimSpace(location.TrailingComments)
+	if trailing != "" && !strings.Contains(trailing, "\n") {
+		d.text.WriteString(indent + line + " // " + trailing + "\n")
+		return
+	}
+	d.text.WriteString(indent + line + "\n")
+	d.writeComments(location.TrailingComments, indent+"  ")
+}
+
+func (d *protoDescriber) writeComments(comments string, indent string) {
+	comments = strings.TrimRight(comments, " \n")
+	if strings.TrimSpace(comments) == "" {
+		return
+	}
+	for _, line := range strings.Split(comments, "\n") {
+		d.text.WriteString(strings.TrimRight(indent+"//"+line, " ") + "\n")
+	}
+}
@@ -25,6 +25,9 @@ const protoFileExtension = ".proto"
 
 const WellKnownEmptyMessageType = "google.protobuf.Empty"
 
+// The comments of the .proto files are only kept when they are shown, e.g. via describe.
+var protoSourceInfoIncluded = false
+
 /*
 Given a directory of .proto files, we use `protoc` to convert these to
 an equivalent FileDescriptorSet payload where imports have been resolved.
@@ -39,9 +42,36 @@ See:
 	https://pkg.go.dev/google.golang.org/protobuf/reflect/protoregistry
 */
 
+func convertProtoFilesToProtoRegistryFiles() *protoregistry.Files {
+	var protoFileDescriptorSet *descriptorpb.FileDescriptorSet
+	if len(CurrentConfig.DescriptorSetFiles) != 0 {
+		protoFileDescriptorSet = readPrecompiledFileDescriptorSets(CurrentConfig.DescriptorSetFiles)
+	} else if CurrentConfig.ForceNoProtoc {
+		protoFileDescriptorSet = compileProtoFilesInternallyToFileDescriptorSet()
+	} else {
+		protoFileDescriptorSet = convertProtoFilesToFileDescriptorSetViaProtoc()
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("%s .proto descriptor %s\n%s\n", VISUAL_SEPARATOR, VISUAL_SEPARATOR, strings.TrimSpace(prototext.Format(protoFileDescriptorSet)))
+	}
+
+	protoRegistryFiles, err := protodesc.NewFiles(protoFileDescriptorSet)
+	PanicOnError(err)
+
+	if CurrentConfig.DecodeRawResponse {
+		if CurrentConfig.Verbose {
+			fmt.Printf("Adding %s to proto registry to ensure it can be used for decoding raw Protobuf.\n", WellKnownEmptyMessageType)
+		}
+		_ = protoRegistryFiles.RegisterFile(wellKnownEmptyMessageProtoFileDescriptorForRawFormat())
+	}
+
+	return protoRegistryFiles
+}
+
 // Read the given proto file as a FileDescriptorSet so that we work with it within Go's SDK.
 // protoc --include_imports -o/out.bin -I /proto new-file.proto
-func convertProtoFilesToProtoRegistryFiles() *protoregistry.Files {
+func convertProtoFilesToFileDescriptorSetViaProtoc() *descriptorpb.FileDescriptorSet {
 
 	protocPath, isBundled := findProtocExecutable()
 
@@ -63,6 +93,9 @@ func convertProtoFilesToProtoRegistryFiles() *protoregistry.Files {
 		"-I", googleProtobufInclude,
 		"-I", CurrentConfig.ProtoFilesDir,
 	}
+	if protoSourceInfoIncluded {
+		protocArgs = append(protocArgs, "--include_source_info")
+	}
 	protocArgs = append(protocArgs, protoFilesArgs...)
 
 	protocErr := bytes.NewBuffer([]byte{})
@@ -91,21 +124,42 @@ func convertProtoFilesToProtoRegistryFiles() *protoregistry.Files {
 	err = proto.Unmarshal(inputFileBin, &protoFileDescriptorSet)
 	PanicOnError(err)
 
-	if CurrentConfig.Verbose {
-		fmt.Printf("%s .proto descriptor %s\n%s\n", VISUAL_SEPARATOR, VISUAL_SEPARATOR, strings.TrimSpace(prototext.Format(&protoFileDescriptorSet)))
-	}
+	return &protoFileDescriptorSet
+}
 
-	protoRegistryFiles, err := protodesc.NewFiles(&protoFileDescriptorSet)
-	PanicOnError(err)
+// Reads FileDescriptorSets created via protoc --include_imports -o/out.bin ... and merges them into one.
+// Files contained in multiple sets are only taken from the first set.
+func readPrecompiledFileDescriptorSets(descriptorSetFiles []string) *descriptorpb.FileDescriptorSet {
+	mergedFileDescriptorSet := descriptorpb.FileDescriptorSet{}
+	alreadyAdded := make(map[string]bool)
 
-	if CurrentConfig.DecodeRawResponse {
+	for _, descriptorSetFile := range descriptorSetFiles {
 		if CurrentConfig.Verbose {
-			fmt.Printf("Adding %s to proto registry to ensure it can be used for decoding raw Protobuf.\n", WellKnownEmptyMessageType)
+			fmt.Printf("Reading FileDescriptorSet from %s.\n", descriptorSetFile)
+		}
+
+		descriptorSetBin, err := os.ReadFile(descriptorSetFile)
+		PanicOnError(err)
+
+		fileDescriptorSet := descriptorpb.FileDescriptorSet{}
+		err = proto.Unmarshal(descriptorSetBin, &fileDescriptorSet)
+		PanicWithMessageOnError(err, func() string {
+			return "Could not read " + descriptorSetFile + " as a binary FileDescriptorSet. Was it created via protoc --include_imports -o <file> ?"
+		})
+
+		for _, file := range fileDescriptorSet.File {
+			if alreadyAdded[file.GetName()] {
+				if CurrentConfig.Verbose {
+					fmt.Printf("Skipping %s from %s as it was already provided by a previous FileDescriptorSet.\n", file.GetName(), descriptorSetFile)
+				}
+				continue
+			}
+			alreadyAdded[file.GetName()] = true
+			mergedFileDescriptorSet.File = append(mergedFileDescriptorSet.File, file)
 		}
-		_ = protoRegistryFiles.RegisterFile(wellKnownEmptyMessageProtoFileDescriptorForRawFormat())
 	}
 
-	return protoRegistryFiles
+	return &mergedFileDescriptorSet
 }
 
 func wellKnownEmptyMessageProtoFileDescriptorForRawFormat() protoreflect.FileDescriptor {
@@ -245,3 +299,105 @@ func ensureResolvedMessagesAreUnique(resolvedFullNames *[]string, searchedMessag
 			"Try -v verbose or specify the file explicitly via -f <path-to-proto-file>.", len(*resolvedFullNames), *resolvedFullNames))
 	}
 }
+
+// Resolves a method given as package.Service/Method (notation of gRPC paths), package.Service.Method (full name)
+// or ..Method (unique base name).
+func resolveMethodByName(methodName string, registry *protoregistry.Files) protoreflect.MethodDescriptor {
+	if strings.HasPrefix(methodName, inferredMessagePathPrefix) {
+		return findUniqueMethodByBaseName(registry, strings.TrimPrefix(methodName, inferredMessagePathPrefix))
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Looking up method with full name: %s\n", methodName)
+	}
+
+	fullName := strings.Replace(methodName, "/", ".", 1)
+	descriptor, err := registry.FindDescriptorByName(protoreflect.FullName(fullName))
+	PanicWithMessageOnError(err, func() string {
+		return "I couldn't find any method for " + methodName + ".\n" +
+			"Did you correctly -I (include) your proto files directory?\n" +
+			"Did you specify the method as package.Service/Method?"
+	})
+
+	method, ok := descriptor.(protoreflect.MethodDescriptor)
+	if !ok {
+		PanicWithMessage("Resolved " + methodName + " to " + string(descriptor.FullName()) + " which is not a method of a service.")
+	}
+
+	return method
+}
+
+func findUniqueMethodByBaseName(registry *protoregistry.Files, searchedMethodName string) protoreflect.MethodDescriptor {
+	if CurrentConfig.Verbose {
+		fmt.Printf("Searching for method with base name: %s\n", searchedMethodName)
+	}
+
+	var resolvedMethodDescriptors []protoreflect.MethodDescriptor
+
+	registry.RangeFiles(func(fileDesc protoreflect.FileDescriptor) bool {
+		services := fileDesc.Services()
+		for i := 0; i < services.Len(); i++ {
+			if method := services.Get(i).Methods().ByName(protoreflect.Name(searchedMethodName)); method != nil {
+				resolvedMethodDescriptors = append(resolvedMethodDescriptors, method)
+			}
+		}
+		return true // continue to search the next file
+	})
+
+	var resolvedFullNames []string
+	for _, methodDesc := range resolvedMethodDescriptors {
+		resolvedFullNames = append(resolvedFullNames, string(methodDesc.FullName()))
+	}
+	sort.Strings(resolvedFullNames) // deterministic output for testing
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Resolved method package-paths for name %s: %v\n", searchedMethodName, resolvedFullNames)
+	}
+
+	switch len(resolvedFullNames) {
+	case 0:
+		PanicWithMessage("No method found with base name: " + searchedMethodName + ". Check the folder of proto files (-I) and verbose (-v).")
+	case 1: /* do-nothing */
+	default:
+		PanicWithMessage(fmt.Sprintf("Method with base name is not unique. Found %d methods with package paths: %v\n"+
+			"Try -v verbose or specify the method via package.Service/Method.", len(resolvedFullNames), resolvedFullNames))
+	}
+
+	return resolvedMethodDescriptors[0]
+}
+
+// Returns all messages (including nested ones) in the registry sorted by their full names.
+func collectAllMessageDescriptors(registry *protoregistry.Files) (messages []protoreflect.MessageDescriptor) {
+	var collectRecursively func(descriptors protoreflect.MessageDescriptors)
+	collectRecursively = func(descriptors protoreflect.MessageDescriptors) {
+		for i := 0; i < descriptors.Len(); i++ {
+			messages = append(messages, descriptors.Get(i))
+			collectRecursively(descriptors.Get(i).Messages())
+		}
+	}
+
+	registry.RangeFiles(func(fileDesc protoreflect.FileDescriptor) bool {
+		collectRecursively(fileDesc.Messages())
+		return true
+	})
+
+	sort.Slice(messages, func(i, j int) bool { return messages[i].FullName() < messages[j].FullName() })
+	return
+}
+
+// Returns all methods of all services in the registry sorted by their full names.
+func collectAllMethodDescriptors(registry *protoregistry.Files) (methods []protoreflect.MethodDescriptor) {
+	registry.RangeFiles(func(fileDesc protoreflect.FileDescriptor) bool {
+		services := fileDesc.Services()
+		for i := 0; i < services.Len(); i++ {
+			serviceMethods := services.Get(i).Methods()
+			for j := 0; j < serviceMethods.Len(); j++ {
+				methods = append(methods, serviceMethods.Get(j))
+			}
+		}
+		return true
+	})
+
+	sort.Slice(methods, func(i, j int) bool { return methods[i].FullName() < methods[j].FullName() })
+	return
+}
@@ -4,45 +4,84 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 const GithubRepositoryLink = "https://github.com/qaware/protocurl"
 const EnhancementsAndBugsLink = "https://github.com/qaware/protocurl/issues"
 
 type Config struct {
-	ProtoFilesDir        string
-	ProtoInputFilePath   string
-	RequestType          string
-	ResponseType         string
-	Url                  string
-	Method               string
-	DataText             string
-	InTextType           InTextType
-	OutTextType          OutTextType
-	DecodeRawResponse    bool
-	DisplayBinaryAndHttp bool
-	NoDefaultHeaders     bool
-	RequestHeaders       []string
-	CustomCurlPath       string
-	AdditionalCurlArgs   string
-	Verbose              bool
-	ShowOutputOnly       bool
-	SilentMode           bool
-	ForceNoCurl          bool
-	ForceCurl            bool
-	GlobalProtoc         bool
-	CustomProtocPath     string
-	InferProtoFiles      bool
+	ProtoFilesDir         string
+	ProtoInputFilePath    string
+	RequestType           string
+	ResponseType          string
+	Url                   string
+	Method                string
+	DataText              string
+	InTextType            InTextType
+	OutTextType           OutTextType
+	DecodeRawResponse     bool
+	DisplayBinaryAndHttp  bool
+	NoDefaultHeaders      bool
+	RequestHeaders        []string
+	CustomCurlPath        string
+	AdditionalCurlArgs    string
+	Verbose               bool
+	ShowOutputOnly        bool
+	SilentMode            bool
+	ForceNoCurl           bool
+	ForceCurl             bool
+	GlobalProtoc          bool
+	CustomProtocPath      string
+	ForceNoProtoc         bool
+	DescriptorSetFiles    []string
+	GrpcMethod            string
+	RpcMethod             string
+	ExpectedResponse      string
+	ExpectPartialMatch    bool
+	ExpectedStatusCode    int
+	ExpectedHeaders       []string
+	CaCertFile            string
+	ClientCertFile        string
+	ClientKeyFile         string
+	InsecureSkipVerify    bool
+	TlsServerName         string
+	ConnectTimeoutSeconds float64
+	MaxTimeSeconds        float64
+	MaxAttempts           int
+	RetryDelaySeconds     float64
+	RetryStatusCodes      []int
+	RetryErrors           []string
+	AcceptedStatusCodes   []int
+	ErrorType             string
+	StatusContentTypes    []string
+	Delimited             bool
+	StreamFormat          string
+	RequestEncoding       string
+	ResponseEncoding      string
+	WireFormat            string
+	SuggestTypes          bool
+	FuzzRequests          int
+	RandomSeed            int64
+	RandomMaxDepth        int
+	RandomMaxElements     int
+	RandomMaxLength       int
+	RandomMalformed       bool
+	InferProtoFiles       bool
 }
 
 var commit string
 var version string
 
 var DefaultContentType = "application/x-protobuf"
-var DefaultHeaders = []string{"Content-Type: " + DefaultContentType} // first element needs to be content type, for checks in httpRequest.go
+var DefaultHeaders = []string{"Content-Type: " + DefaultContentType}
+
+var GrpcContentType = "application/grpc"
+var GrpcDefaultHeaders = []string{"Content-Type: " + GrpcContentType, "TE: trailers"}
 
 var CurrentConfig = Config{}
 
@@ -50,7 +89,7 @@ func main() {
 	defer func() {
 		if err := recover(); err != nil {
 			PrintError(fmt.Errorf("%v", err))
-			os.Exit(1)
+			os.Exit(ExitCodeOf(err))
 		}
 	}()
 	PanicOnError(rootCmd.Execute())
@@ -64,18 +103,27 @@ func init() {
 var rootCmd = &cobra.Command{
 	Short: "protoCURL is cURL for Protobuf: The command-line tool for interacting with Protobuf over HTTP REST endpoints using human-readable text formats.",
 	Use: "protocurl [flags] -I proto-dir -i request-type -o response-type -u url -d request-text\n\n" +
-		"It uses '" + CurlExecutableName + "' from PATH. If none was found, it will fall back to an internal non-configurable http request.\n" +
+		"It uses '" + CurlExecutableName + "' from PATH. If none was found, it will fall back to an internal http request which supports the same methods and headers.\n" +
 		"It uses a bundled '" + ProtocExecutableName + "' (by default) which is used to parse the .proto files.\n" +
 		"The bundle also includes the well-known Google Protobuf files necessary to create FileDescriptorSet payloads via '" + ProtocExecutableName + "'.\n" +
 		"If the bundled '" + ProtocExecutableName + "' is used, then these .proto files are included. Otherwise .proto files from the system-wide include are used.\n" +
+		"Alternatively, --no-protoc uses a built-in .proto compiler which needs neither '" + ProtocExecutableName + "' nor the bundled files.\n" +
+		"Precompiled FileDescriptorSet files can be used via --descriptor-set instead of any .proto files.\n" +
 		"The Header 'Content-Type: application/x-protobuf' is set as a request header by default. (disable via -n)\n" +
+		"Via --wire-format json or text, the request is sent in JSON or the Protobuf text format instead. The response is decoded according to its Content-Type.\n" +
+		"Unary gRPC methods can be invoked via --grpc package.Service/Method. The request and response types are inferred from the method then.\n" +
+		"Similarly, --rpc infers them for HTTP requests. The method and url path are taken from google.api.http annotations, if present.\n" +
 		"When converting between binary and text, the encoding UTF-8 is always used.\n" +
-		"When the correct response type is unknown or being debugged, omitting -o <response-type> will attempt to show the response in raw format.\n\n" +
-		"Enhancements and bugs: " + EnhancementsAndBugsLink,
+		"When the correct response type is unknown or being debugged, omitting -o <response-type> will attempt to show the response in raw format.\n" +
+		"If the response status code is not accepted, then protocurl exits with the exit code " + strconv.Itoa(ExitCodeHttpFailure) + ". Use --error-type to show the error response nonetheless.\n\n" +
+		"Enhancements and bugs: " + EnhancementsAndBugsLink + "\n",
 	Example:               "  protocurl -I my-protos -i package.path.Req -o package.path.Resp -u http://example.com/api -d \"myField: true, otherField: 1337\"",
 	Args:                  cobra.OnlyValidArgs,
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
+		tmpMethodExplicitlyProvided = cmd.Flags().Changed("method")
+		tmpDataMayBePipedViaStdin = true
+
 		propagateFlags()
 
 		printVersionInfoVerbose(cmd)
@@ -88,8 +136,46 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// Converts the .proto files once for subcommands sending multiple requests or converting payloads with the same proto registry.
+func convertProtoFilesToProtoRegistryFilesWithRawFormat() *protoregistry.Files {
+	propagateProtoFileFlags()
+
+	registry := convertProtoFilesToProtoRegistryFiles()
+	// the message type can be omitted for any request or payload. Hence, raw decoding needs to be possible at all times.
+	_ = registry.RegisterFile(wellKnownEmptyMessageProtoFileDescriptorForRawFormat())
+	return registry
+}
+
+// Runs a single request of a subcommand sending multiple requests. CurrentConfig needs to be populated as if parsed from flags.
+func runSingleRequestWithRegistry(registry *protoregistry.Files) {
+	propagateFlags()
+
+	addDefaultHeaderArgument()
+
+	printArgsVerbose()
+
+	runProtocurlWorkflowWithRegistry(registry)
+}
+
 func runProtocurlWorkflow() {
-	protoRegistryFiles := convertProtoFilesToProtoRegistryFiles()
+	runProtocurlWorkflowWithRegistry(convertProtoFilesToProtoRegistryFiles())
+}
+
+func runProtocurlWorkflowWithRegistry(protoRegistryFiles *protoregistry.Files) {
+	rpcHttpRule = nil
+
+	if CurrentConfig.FuzzRequests > 0 {
+		runFuzzRequests(protoRegistryFiles)
+		return
+	}
+
+	if CurrentConfig.GrpcMethod != "" {
+		resolveGrpcMethodAndInferMessageTypes(protoRegistryFiles)
+	}
+
+	if CurrentConfig.RpcMethod != "" {
+		resolveRpcMethodAndInferRequestDetails(protoRegistryFiles)
+	}
 
 	var requestBinary []byte // empty iff no body was provided or if an empty body was provided.
 	if CurrentConfig.Method == "GET" && CurrentConfig.RequestType == "" {
@@ -98,24 +184,74 @@ func runProtocurlWorkflow() {
 		requestBinary = encodeToBinary(CurrentConfig.RequestType, CurrentConfig.DataText, protoRegistryFiles)
 	}
 
-	responseBinary, responseHeaders := invokeHttpRequestBasedOnConfig(requestBinary)
+	if rpcHttpRule != nil {
+		if CurrentConfig.Delimited {
+			PanicWithMessage("The google.api.http annotation of " + CurrentConfig.RpcMethod + " cannot be applied to a delimited stream of messages.")
+		}
+		CurrentConfig.Url, requestBinary = applyHttpRule(*rpcHttpRule, requestBinary, protoRegistryFiles)
+	}
+
+	requestBody := encodeRequestBody(encodeRequestWireFormat(requestBinary, protoRegistryFiles))
 
-	decodeResponse(responseBinary, responseHeaders, protoRegistryFiles)
+	if CurrentConfig.StreamFormat != "" {
+		invokeStreamingHttpRequest(requestBody, protoRegistryFiles)
+		return
+	}
+
+	responseBody, responseHeaders := invokeHttpRequestBasedOnConfig(requestBody)
+	responseBinary := decodeResponseWireFormat(decodeResponseBody(responseBody, responseHeaders), responseHeaders, protoRegistryFiles)
+
+	var responseMsg *dynamicpb.Message
+	if CurrentConfig.GrpcMethod == "" {
+		responseMsg = decodeErrorOrStatusResponse(responseBinary, responseHeaders, protoRegistryFiles)
+		ensureStatusCodeIsAccepted(responseHeaders)
+	}
+
+	if responseMsg == nil {
+		if CurrentConfig.Delimited {
+			decodeDelimitedResponse(responseBinary, responseHeaders, protoRegistryFiles)
+		} else if isUntypedWireFormatResponse(responseBinary, responseHeaders) {
+			displayWireFormatResponse(responseBinary, responseHeaders)
+		} else {
+			responseMsg = decodeResponse("Response", "", responseBinary, responseHeaders, protoRegistryFiles)
+			if CurrentConfig.SuggestTypes {
+				displayMessageTypeSuggestions(CurrentConfig.Method+" Response Type Suggestions", responseBinary, protoRegistryFiles)
+			}
+		}
+	}
+
+	if hasResponseExpectations() {
+		ensureResponseMeetsExpectations(responseMsg, responseHeaders, protoRegistryFiles)
+	}
 }
 
 func encodeToBinary(requestType string, text string, registry *protoregistry.Files) []byte {
-	requestBinary, _ := textToMsgAndBinary(requestType, text, registry)
+	var requestBinary []byte
+	displayedInTextType := CurrentConfig.InTextType
+	if CurrentConfig.InTextType == IBinary {
+		requestBinary = []byte(text) // sent as it is. Decoding it below ensures, that it matches the request type.
+		displayedInTextType = IText
+	} else if CurrentConfig.Delimited {
+		requestBinary = textToDelimitedBinary(requestType, text, registry)
+	} else {
+		requestBinary, _ = textToMsgAndBinary(requestType, text, registry)
+	}
 
-	reconstructedRequestText, _ := protoBinaryToMsgAndText(
-		requestType,
-		requestBinary,
-		OutTextType(CurrentConfig.InTextType),
-		registry,
-	)
+	var reconstructedRequestText string
+	if CurrentConfig.Delimited {
+		reconstructedRequestText = delimitedBinaryToText(requestType, requestBinary, OutTextType(displayedInTextType), registry)
+	} else {
+		reconstructedRequestText, _ = protoBinaryToMsgAndText(
+			requestType,
+			requestBinary,
+			OutTextType(displayedInTextType),
+			registry,
+		)
+	}
 
 	if !CurrentConfig.ShowOutputOnly && !CurrentConfig.SilentMode {
 		fmt.Printf("%s %s Request  %s    %s %s\n%s\n",
-			VISUAL_SEPARATOR, CurrentConfig.Method, displayIn(CurrentConfig.InTextType), VISUAL_SEPARATOR,
+			VISUAL_SEPARATOR, CurrentConfig.Method, displayIn(displayedInTextType), VISUAL_SEPARATOR,
 			SEND, reconstructedRequestText)
 	}
 
@@ -127,6 +263,10 @@ func encodeToBinary(requestType string, text string, registry *protoregistry.Fil
 }
 
 func invokeHttpRequestBasedOnConfig(requestBinary []byte) ([]byte, string) {
+	if CurrentConfig.GrpcMethod != "" {
+		return invokeGrpcRequest(requestBinary)
+	}
+
 	if CurrentConfig.ForceNoCurl {
 		if CurrentConfig.Verbose {
 			fmt.Println("Using internal http request due to forced avoidance of curl.")
@@ -150,20 +290,55 @@ func invokeHttpRequestBasedOnConfig(requestBinary []byte) ([]byte, string) {
 	}
 }
 
-func decodeResponse(responseBinary []byte, responseHeaders string, registry *protoregistry.Files) {
-	if CurrentConfig.DisplayBinaryAndHttp && !CurrentConfig.SilentMode {
-		fmt.Printf("%s %s Response Headers %s %s\n%s\n", VISUAL_SEPARATOR, CurrentConfig.Method, VISUAL_SEPARATOR, RECV, responseHeaders)
+// Decodes the response via --error-type or as google.rpc.Status, if applicable. Only the latter is returned.
+func decodeErrorOrStatusResponse(responseBinary []byte, responseHeaders string, registry *protoregistry.Files) *dynamicpb.Message {
+	accepted := isStatusCodeAccepted(statusCodeOfHeaders(responseHeaders))
+	if !accepted && CurrentConfig.ErrorType != "" {
+		decodeResponse("Error Response", CurrentConfig.ErrorType, responseBinary, responseHeaders, registry)
+	} else if hasGoogleRpcStatusContentType(responseHeaders) || (!accepted && isRecognisedAsGoogleRpcStatus(responseBinary, registry)) {
+		title := "Status"
+		if !accepted {
+			title = "Error Status"
+		}
+		return decodeGoogleRpcStatus(title, responseBinary, responseHeaders, registry)
+	}
+	return nil
+}
 
-		fmt.Printf("%s %s Response Binary  %s %s\n%s", VISUAL_SEPARATOR, CurrentConfig.Method, VISUAL_SEPARATOR, RECV, hex.Dump(responseBinary))
+// Decodes the response body with the given message type and shows it with the given title.
+// Without a message type, the response type is used or the response is decoded raw.
+func decodeResponse(title string, messageType string, responseBinary []byte, responseHeaders string, registry *protoregistry.Files) *dynamicpb.Message {
+	displayResponseHeadersAndBinary(responseBinary, responseHeaders)
+
+	if messageType == "" {
+		messageType = properResponseTypeIfProvidedOrEmptyType()
 	}
 
-	responseMessageType := properResponseTypeIfProvidedOrEmptyType()
+	responseText, responseMsg := protoBinaryToMsgAndText(messageType, responseBinary, CurrentConfig.OutTextType, registry)
 
-	responseText, _ := protoBinaryToMsgAndText(responseMessageType, responseBinary, CurrentConfig.OutTextType, registry)
+	displayDecodedResponse(title, CurrentConfig.OutTextType, responseText)
 
+	return responseMsg
+}
+
+func displayResponseHeadersAndBinary(responseBinary []byte, responseHeaders string) {
+	displayResponseHeaders(responseHeaders)
+
+	if CurrentConfig.DisplayBinaryAndHttp && !CurrentConfig.SilentMode {
+		fmt.Printf("%s %s Response Binary  %s %s\n%s", VISUAL_SEPARATOR, CurrentConfig.Method, VISUAL_SEPARATOR, RECV, hex.Dump(responseBinary))
+	}
+}
+
+func displayResponseHeaders(responseHeaders string) {
+	if CurrentConfig.DisplayBinaryAndHttp && !CurrentConfig.SilentMode {
+		fmt.Printf("%s %s Response Headers %s %s\n%s\n", VISUAL_SEPARATOR, CurrentConfig.Method, VISUAL_SEPARATOR, RECV, responseHeaders)
+	}
+}
+
+func displayDecodedResponse(title string, outFormat OutTextType, responseText string) {
 	if !CurrentConfig.ShowOutputOnly && !CurrentConfig.SilentMode {
-		fmt.Printf("%s %s Response %s    %s %s\n",
-			VISUAL_SEPARATOR, CurrentConfig.Method, displayOut(CurrentConfig.OutTextType), VISUAL_SEPARATOR, RECV)
+		fmt.Printf("%s %s %s %s    %s %s\n",
+			VISUAL_SEPARATOR, CurrentConfig.Method, title, displayOut(outFormat), VISUAL_SEPARATOR, RECV)
 	}
 	if !CurrentConfig.SilentMode {
 		fmt.Printf("%s\n", responseText)
@@ -186,13 +361,20 @@ func addDefaultHeaderArgument() {
 		return
 	}
 
+	defaultHeaders := DefaultHeaders
+	if CurrentConfig.GrpcMethod != "" {
+		defaultHeaders = GrpcDefaultHeaders
+	} else if CurrentConfig.WireFormat != WireBinary {
+		defaultHeaders = wireFormatDefaultHeaders(CurrentConfig.WireFormat)
+	}
+
 	if CurrentConfig.Verbose {
-		fmt.Printf("Adding default header argument to request headers : %s\n", DefaultHeaders)
+		fmt.Printf("Adding default header argument to request headers : %s\n", defaultHeaders)
 	}
-	CurrentConfig.RequestHeaders = append(DefaultHeaders, CurrentConfig.RequestHeaders...)
+	CurrentConfig.RequestHeaders = append(defaultHeaders, CurrentConfig.RequestHeaders...)
 }
 
 func setAndShowVersion() {
 	rootCmd.Version = fmt.Sprintf("%s, build %s, %s", version, commit[:6], GithubRepositoryLink)
-	rootCmd.SetHelpTemplate("protocurl {{.Version}}\n\n" + rootCmd.HelpTemplate())
+	rootCmd.SetHelpTemplate("protocurl {{.Root.Version}}\n\n" + rootCmd.HelpTemplate())
 }
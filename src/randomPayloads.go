@@ -0,0 +1,450 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+For robustness testing, random instances of a message type are generated from its descriptor via dynamicpb.
+The random subcommand prints a single instance. --fuzz <n> sends n requests with random bodies of the request
+type and summarises the status codes of their responses.
+
+The generation is deterministic for a given seed. Without a seed, a random one is chosen and shown, so that a run
+can be reproduced. The depth of nested messages, the number of elements of repeated fields and maps as well as
+the length of strings and bytes are limited.
+
+Most fields are set, but some are left unset. Of each oneof, one or no alternative is set.
+Messages beyond the depth are left empty except for their required scalar fields. Since self-recursive required
+fields cannot be filled at any depth, the payloads may still lack required message fields. They are marshalled nonetheless.
+Well-known types with restricted values, such as google.protobuf.Timestamp, are generated within their valid ranges.
+google.protobuf.Any and google.protobuf.FieldMask are left empty.
+
+With --malformed, a deliberately malformed field is appended to the valid payload: A field with a wrong wire type,
+a truncated varint, a length-delimited field exceeding the payload or a string field with invalid UTF-8.
+*/
+
+const (
+	MalformedWrongWireType   = "wrong wire type"
+	MalformedTruncatedVarint = "truncated varint"
+	MalformedTruncatedBytes  = "truncated length-delimited field"
+	MalformedInvalidUtf8     = "invalid UTF-8"
+)
+
+var randomMarshalOptions = proto.MarshalOptions{
+	Deterministic: true, // reproducible for a given seed
+	AllowPartial:  true,
+}
+
+// Characters of random strings. Multi-byte characters ensure that the UTF-8 encoding is exercised.
+var randomStringRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 -_.äöüß€⭐😀")
+
+var randomMessageType string
+
+var randomCmd = &cobra.Command{
+	Short: "Generates a random payload of a message type.",
+	Use: "random [flags] -t message-type\n\n" +
+		"Writes the binary payload to stdout or --output-file. Use --out to show it in the Protobuf text format or JSON instead.\n" +
+		"The payload is deterministic for a given --seed. With --malformed, a malformed field is appended to the payload.",
+	Example:               "  protocurl random -I my-protos -t ..MyRequest --seed 42 --out text",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		ensureRandomPayloadFlagsAreValid()
+		if tmpOutTextType != "" && CurrentConfig.RandomMalformed {
+			PanicWithMessage("A malformed payload cannot be shown in a text format. Please avoid --out together with --malformed.")
+		}
+
+		propagateProtoFileFlags()
+		registry := convertProtoFilesToProtoRegistryFiles()
+		descriptor := *resolveMessageByName(randomMessageType, registry)
+
+		generator := newRandomPayloadGenerator()
+		msg := generator.message(descriptor)
+		if tmpOutTextType == "" {
+			writeConversionOutput(formatBinary(generator.payload(msg)))
+			return
+		}
+
+		text, err := partialMsgToText(msg, conversionOutTextType(), registry)
+		PanicOnError(err)
+		writeConversionOutput([]byte(text + "\n"))
+	},
+}
+
+func initialiseRandomCommand() {
+	flags := randomCmd.Flags()
+	addProtoFileFlags(flags)
+	addVerboseFlag(flags)
+	addRandomPayloadFlags(flags, "")
+
+	flags.StringVarP(&randomMessageType, "type", "t", "",
+		"Mandatory: Message name or full package path of the Protobuf message type. The path can be shortened to '..', if the name of the message is unique.")
+	AssertSuccess(randomCmd.MarkFlagRequired("type"))
+
+	flags.StringVar(&conversionBinaryFormat, "binary-format", BinaryRaw,
+		"The representation of the binary payload. '"+BinaryRaw+"' uses the bytes as they are. '"+BinaryHex+"', '"+BinaryBase64+"' and '"+BinaryBase64Url+"' use the respective encodings.")
+
+	flags.StringVar(&conversionOutputFile, "output-file", "",
+		"Writes the output to the given `file` instead of stdout.")
+
+	flags.StringVar(&tmpOutTextType, "out", "",
+		"Shows the payload in the specified format instead of the binary format. 'text' produces Protobuf text format. 'json' produces dense JSON and "+
+			"'json:pretty' produces pretty-printed JSON.")
+
+	rootCmd.AddCommand(randomCmd)
+}
+
+// The flags are shared by the random subcommand and --fuzz. The latter uses the prefix fuzz- for them.
+func addRandomPayloadFlags(flags *pflag.FlagSet, prefix string) {
+	flags.Int64Var(&CurrentConfig.RandomSeed, prefix+"seed", 0,
+		"The seed of the random payloads. The same seed produces the same payloads. 0 chooses a random seed.")
+
+	flags.IntVar(&CurrentConfig.RandomMaxDepth, prefix+"max-depth", 3,
+		"The maximum `depth` of nested messages in the random payloads.")
+
+	flags.IntVar(&CurrentConfig.RandomMaxElements, prefix+"max-elements", 3,
+		"The maximum `number` of elements of repeated fields and maps in the random payloads.")
+
+	flags.IntVar(&CurrentConfig.RandomMaxLength, prefix+"max-length", 16,
+		"The maximum `length` of strings (in characters) and bytes in the random payloads.")
+
+	flags.BoolVar(&CurrentConfig.RandomMalformed, prefix+"malformed", false,
+		"Appends a malformed field to the random payloads: "+MalformedWrongWireType+", "+MalformedTruncatedVarint+", "+MalformedTruncatedBytes+" or "+MalformedInvalidUtf8+".")
+}
+
+func ensureRandomPayloadFlagsAreValid() {
+	if CurrentConfig.RandomMaxDepth < 0 || CurrentConfig.RandomMaxElements < 0 || CurrentConfig.RandomMaxLength < 0 {
+		PanicWithMessage("The limits of the random payloads must not be negative.")
+	}
+}
+
+type randomPayloadGenerator struct {
+	random *rand.Rand
+}
+
+func newRandomPayloadGenerator() *randomPayloadGenerator {
+	if CurrentConfig.RandomSeed == 0 {
+		CurrentConfig.RandomSeed = time.Now().UnixNano()
+	}
+	if CurrentConfig.Verbose {
+		fmt.Printf("Generating random payloads with seed %d.\n", CurrentConfig.RandomSeed)
+	}
+	return &randomPayloadGenerator{random: rand.New(rand.NewSource(CurrentConfig.RandomSeed))}
+}
+
+// Returns the binary format of the random message. It is malformed, if requested.
+func (g *randomPayloadGenerator) payload(msg *dynamicpb.Message) []byte {
+	binary, err := randomMarshalOptions.Marshal(msg)
+	PanicOnError(err)
+
+	if CurrentConfig.RandomMalformed {
+		var kind string
+		binary, kind = g.malform(binary, msg.Descriptor())
+		if CurrentConfig.Verbose {
+			fmt.Printf("Appended a malformed field with %s to the random payload.\n", kind)
+		}
+	}
+	return binary
+}
+
+func (g *randomPayloadGenerator) message(descriptor protoreflect.MessageDescriptor) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(descriptor)
+	g.fillMessage(msg, CurrentConfig.RandomMaxDepth)
+	return msg
+}
+
+func (g *randomPayloadGenerator) fillMessage(msg protoreflect.Message, depth int) {
+	fields := msg.Descriptor().Fields()
+	switch msg.Descriptor().FullName() {
+	case "google.protobuf.Timestamp": // 0001-01-01 to 9999-12-31
+		msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(g.random.Int63n(253402300800+62135596800)-62135596800))
+		msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(g.random.Int31n(1e9)))
+		return
+	case "google.protobuf.Duration": // up to 10000 years. The nanos need the same sign as the seconds.
+		seconds := g.random.Int63n(2*315576000000+1) - 315576000000
+		nanos := g.random.Int31n(1e9)
+		if seconds < 0 {
+			nanos = -nanos
+		}
+		msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(seconds))
+		msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(nanos))
+		return
+	case "google.protobuf.Any", "google.protobuf.FieldMask":
+		return
+	}
+
+	// google.protobuf.Value needs exactly one alternative for the JSON mapping. Other oneofs may remain unset.
+	chosenAlternatives := map[protoreflect.FullName]int{}
+	oneofs := msg.Descriptor().Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		alternatives := oneofs.Get(i).Fields().Len()
+		if msg.Descriptor().FullName() != "google.protobuf.Value" {
+			alternatives++ // none of the alternatives
+		}
+		chosenAlternatives[oneofs.Get(i).FullName()] = g.random.Intn(alternatives)
+	}
+
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			if chosen := chosenAlternatives[oneof.FullName()]; chosen == oneof.Fields().Len() || oneof.Fields().Get(chosen) != field {
+				continue
+			}
+		} else if field.Cardinality() != protoreflect.Required && g.random.Intn(4) == 0 {
+			continue
+		}
+
+		switch {
+		case field.IsMap():
+			if field.MapValue().Message() != nil && depth == 0 {
+				continue
+			}
+			entries := msg.Mutable(field).Map()
+			for n := g.random.Intn(CurrentConfig.RandomMaxElements + 1); n > 0; n-- {
+				value := entries.NewValue()
+				if field.MapValue().Message() == nil {
+					value = g.scalar(field.MapValue())
+				} else {
+					g.fillMessage(value.Message(), depth-1)
+				}
+				entries.Set(g.scalar(field.MapKey()).MapKey(), value)
+			}
+		case field.IsList():
+			if field.Message() != nil && depth == 0 {
+				continue
+			}
+			elements := msg.Mutable(field).List()
+			for n := g.random.Intn(CurrentConfig.RandomMaxElements + 1); n > 0; n-- {
+				if field.Message() == nil {
+					elements.Append(g.scalar(field))
+				} else {
+					element := elements.NewElement()
+					g.fillMessage(element.Message(), depth-1)
+					elements.Append(element)
+				}
+			}
+		case field.Message() != nil:
+			nested := msg.Mutable(field).Message() // messages beyond the depth only contain required scalars
+			if depth > 0 {
+				g.fillMessage(nested, depth-1)
+			} else {
+				g.fillRequiredScalars(nested)
+			}
+		default:
+			msg.Set(field, g.scalar(field))
+		}
+	}
+}
+
+// Required message fields are not filled, as they may be recursive.
+func (g *randomPayloadGenerator) fillRequiredScalars(msg protoreflect.Message) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if field := fields.Get(i); field.Cardinality() == protoreflect.Required && field.Message() == nil {
+			msg.Set(field, g.scalar(field))
+		}
+	}
+}
+
+// Random payloads may lack required fields. Hence, they are converted without checking them.
+func partialMsgToText(msg proto.Message, outFormat OutTextType, registry *protoregistry.Files) (string, error) {
+	resolver := newRegistryTypeResolver(registry)
+	var textBytes []byte
+	var err error
+	if outFormat == OText {
+		textOpts := textFormatOptions // shallow copy
+		textOpts.Resolver = resolver
+		textOpts.AllowPartial = true
+		textBytes, err = textOpts.Marshal(msg)
+	} else {
+		jsonOpts := jsonDenseformatOptions // shallow copy
+		if outFormat == OJsonPretty {
+			jsonOpts = jsonPrettyformatOptions
+		}
+		jsonOpts.Resolver = resolver
+		jsonOpts.AllowPartial = true
+		textBytes, err = jsonOpts.Marshal(msg)
+	}
+	return strings.TrimSuffix(string(textBytes), "\n"), err
+}
+
+// Integers are shifted by a random amount, so that small as well as large magnitudes occur.
+func (g *randomPayloadGenerator) scalar(field protoreflect.FieldDescriptor) protoreflect.Value {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(g.random.Intn(2) == 1)
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		return protoreflect.ValueOfEnum(values.Get(g.random.Intn(values.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(int64(g.random.Uint64()) >> (32 + g.random.Intn(32))))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(g.random.Uint64() >> (32 + g.random.Intn(32))))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(int64(g.random.Uint64()) >> g.random.Intn(64))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(g.random.Uint64() >> g.random.Intn(64))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(g.randomFloat()))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(g.randomFloat())
+	case protoreflect.StringKind:
+		runes := make([]rune, g.random.Intn(CurrentConfig.RandomMaxLength+1))
+		for i := range runes {
+			runes[i] = randomStringRunes[g.random.Intn(len(randomStringRunes))]
+		}
+		return protoreflect.ValueOfString(string(runes))
+	default: // bytes
+		bytes := make([]byte, g.random.Intn(CurrentConfig.RandomMaxLength+1))
+		g.random.Read(bytes)
+		return protoreflect.ValueOfBytes(bytes)
+	}
+}
+
+func (g *randomPayloadGenerator) randomFloat() float64 {
+	return g.random.NormFloat64() * math.Pow(10, float64(g.random.Intn(7)-3))
+}
+
+// Appends a malformed field to the payload. Returns the malformed payload and the kind of the malformation.
+func (g *randomPayloadGenerator) malform(binary []byte, descriptor protoreflect.MessageDescriptor) ([]byte, string) {
+	var numbers []protowire.Number
+	var stringNumbers []protowire.Number
+	for i := 0; i < descriptor.Fields().Len(); i++ {
+		field := descriptor.Fields().Get(i)
+		numbers = append(numbers, field.Number())
+		if field.Kind() == protoreflect.StringKind {
+			stringNumbers = append(stringNumbers, field.Number())
+		}
+	}
+	if len(numbers) == 0 {
+		numbers = []protowire.Number{1}
+	}
+	number := numbers[g.random.Intn(len(numbers))]
+
+	kinds := []string{MalformedWrongWireType, MalformedTruncatedVarint, MalformedTruncatedBytes}
+	if len(stringNumbers) != 0 {
+		kinds = append(kinds, MalformedInvalidUtf8)
+	}
+
+	kind := kinds[g.random.Intn(len(kinds))]
+	switch kind {
+	case MalformedWrongWireType:
+		field := descriptor.Fields().ByNumber(number)
+		wireTypes := []protowire.Type{protowire.VarintType, protowire.Fixed32Type, protowire.Fixed64Type, protowire.BytesType}
+		var wrongWireTypes []protowire.Type
+		for _, wireType := range wireTypes {
+			if field == nil || !wireTypeMatches(field, wireType) {
+				wrongWireTypes = append(wrongWireTypes, wireType)
+			}
+		}
+		wireType := wrongWireTypes[g.random.Intn(len(wrongWireTypes))]
+		binary = protowire.AppendTag(binary, number, wireType)
+		switch wireType {
+		case protowire.VarintType:
+			binary = protowire.AppendVarint(binary, g.random.Uint64()>>g.random.Intn(64))
+		case protowire.Fixed32Type:
+			binary = protowire.AppendFixed32(binary, g.random.Uint32())
+		case protowire.Fixed64Type:
+			binary = protowire.AppendFixed64(binary, g.random.Uint64())
+		default:
+			value := make([]byte, 1+g.random.Intn(CurrentConfig.RandomMaxLength+1))
+			g.random.Read(value)
+			binary = protowire.AppendBytes(binary, value)
+		}
+		kind += " for field " + strconv.Itoa(int(number))
+	case MalformedTruncatedVarint:
+		binary = protowire.AppendTag(binary, number, protowire.VarintType)
+		binary = append(binary, 0xff, 0xff) // continuation bits without a final byte
+	case MalformedTruncatedBytes:
+		binary = protowire.AppendTag(binary, number, protowire.BytesType)
+		length := 2 + g.random.Intn(CurrentConfig.RandomMaxLength+1)
+		binary = protowire.AppendVarint(binary, uint64(length))
+		binary = append(binary, make([]byte, g.random.Intn(length))...)
+	case MalformedInvalidUtf8:
+		number = stringNumbers[g.random.Intn(len(stringNumbers))]
+		binary = protowire.AppendTag(binary, number, protowire.BytesType)
+		binary = protowire.AppendBytes(binary, []byte{'a', 0xff, 0xfe})
+		kind += " for field " + strconv.Itoa(int(number))
+	}
+	return binary, kind
+}
+
+// Sends the requests with random bodies and summarises the status codes of their responses.
+// In contrast to a single request, the responses are not decoded.
+func runFuzzRequests(registry *protoregistry.Files) {
+	descriptor := *resolveMessageByName(CurrentConfig.RequestType, registry)
+	generator := newRandomPayloadGenerator()
+
+	statusCodeCounts := map[int]int{}
+	notAccepted := 0
+	for i := 1; i <= CurrentConfig.FuzzRequests; i++ {
+		msg := generator.message(descriptor)
+		requestBinary := generator.payload(msg)
+		if CurrentConfig.DisplayBinaryAndHttp && !CurrentConfig.SilentMode {
+			fmt.Printf("%s %s Fuzz Request %d Binary %s %s\n%s", VISUAL_SEPARATOR, CurrentConfig.Method, i, VISUAL_SEPARATOR, SEND, hex.Dump(requestBinary))
+		}
+
+		requestBody := requestBinary
+		if !CurrentConfig.RandomMalformed && CurrentConfig.WireFormat != WireBinary {
+			requestText, err := partialMsgToText(msg, wireFormatOutTextType(), registry)
+			PanicOnError(err)
+			requestBody = []byte(requestText)
+		}
+		_, responseHeaders := invokeHttpRequestBasedOnConfig(encodeRequestBody(requestBody))
+
+		statusCode := statusCodeOfHeaders(responseHeaders)
+		statusCodeCounts[statusCode]++
+		if !isStatusCodeAccepted(statusCode) {
+			notAccepted++
+		}
+
+		if !CurrentConfig.ShowOutputOnly && !CurrentConfig.SilentMode {
+			fmt.Printf("Request %d/%d with %d bytes: %d\n", i, CurrentConfig.FuzzRequests, len(requestBinary), statusCode)
+		}
+	}
+
+	if !CurrentConfig.SilentMode {
+		if !CurrentConfig.ShowOutputOnly {
+			fmt.Printf("%s %s Fuzz Summary %s %s\n", VISUAL_SEPARATOR, CurrentConfig.Method, VISUAL_SEPARATOR, RECV)
+		}
+		fmt.Printf("Sent %d requests with seed %d.\n", CurrentConfig.FuzzRequests, CurrentConfig.RandomSeed)
+		fmt.Println(formatStatusCodeCounts(statusCodeCounts))
+	}
+
+	if notAccepted != 0 {
+		PanicDueToHttpStatus(fmt.Sprintf("%d of %d responses had a status code which is not accepted.", notAccepted, CurrentConfig.FuzzRequests))
+	}
+}
+
+func formatStatusCodeCounts(statusCodeCounts map[int]int) string {
+	var statusCodes []int
+	for statusCode := range statusCodeCounts {
+		statusCodes = append(statusCodes, statusCode)
+	}
+	sort.Ints(statusCodes)
+
+	var lines []string
+	for _, statusCode := range statusCodes {
+		lines = append(lines, fmt.Sprintf("%d: %d responses", statusCode, statusCodeCounts[statusCode]))
+	}
+	return strings.Join(lines, "\n")
+}
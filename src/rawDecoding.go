@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+/*
+Without a message type, the binary payload is decoded raw by only looking at the wire format.
+Since Protobuf is not self-describing, the types of the fields are guessed:
+
+A length-delimited field is shown as a string, if it is printable UTF-8 text. Short texts can often be parsed as
+messages as well, whereas embedded messages almost always contain unprintable bytes due to their lengths and small numbers.
+Otherwise, the field is shown as an embedded message, if it can be completely parsed as one, and as a string or bytes else.
+Varint and fixed-size values are shown as unsigned integers. In the text format, the alternative readings
+which differ from it (signed, zigzag, float and double) are shown as comments.
+The JSON output only contains the unsigned integers, strings, base64-encoded bytes and embedded messages
+keyed by their field numbers. Fields occurring multiple times are shown as lists.
+
+See:
+	https://protobuf.dev/programming-guides/encoding/
+	https://pkg.go.dev/google.golang.org/protobuf/encoding/protowire
+*/
+
+type rawField struct {
+	number   protowire.Number
+	wireType protowire.Type
+	value    uint64     // varint and fixed-size values
+	bytes    []byte     // length-delimited values
+	message  []rawField // length-delimited values parsed as an embedded message and groups
+}
+
+func (f rawField) isMessage() bool {
+	return f.message != nil
+}
+
+func rawBinaryToText(binary []byte, outFormat OutTextType) (string, error) {
+	fields, err := parseRawFields(binary)
+	if err != nil {
+		return "", err
+	}
+
+	switch outFormat {
+	case OJsonDense:
+		return string(formatRawFieldsAsJson(fields)), nil
+	case OJsonPretty:
+		var pretty bytes.Buffer
+		err = json.Indent(&pretty, formatRawFieldsAsJson(fields), "", "  ")
+		return pretty.String(), err
+	default:
+		var text strings.Builder
+		formatRawFieldsAsText(&text, fields, "")
+		return strings.TrimSuffix(text.String(), "\n"), nil
+	}
+}
+
+func parseRawFields(binary []byte) ([]rawField, error) {
+	fields := []rawField{}
+	for len(binary) > 0 {
+		number, wireType, n := protowire.ConsumeTag(binary)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		binary = binary[n:]
+
+		field := rawField{number: number, wireType: wireType}
+		switch wireType {
+		case protowire.VarintType:
+			field.value, n = protowire.ConsumeVarint(binary)
+		case protowire.Fixed32Type:
+			var value uint32
+			value, n = protowire.ConsumeFixed32(binary)
+			field.value = uint64(value)
+		case protowire.Fixed64Type:
+			field.value, n = protowire.ConsumeFixed64(binary)
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(binary)
+			if n >= 0 && len(field.bytes) > 0 && !isPrintableText(field.bytes) {
+				field.message, _ = parseRawFields(field.bytes) // remains nil, if it is not an embedded message
+			}
+		case protowire.StartGroupType:
+			var group []byte
+			group, n = protowire.ConsumeGroup(number, binary)
+			if n >= 0 {
+				var err error
+				if field.message, err = parseRawFields(group); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d of field %d", wireType, number)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		binary = binary[n:]
+
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func formatRawFieldsAsText(text *strings.Builder, fields []rawField, indent string) {
+	for _, field := range fields {
+		switch {
+		case field.isMessage():
+			fmt.Fprintf(text, "%s%d: {\n", indent, field.number)
+			formatRawFieldsAsText(text, field.message, indent+"  ")
+			fmt.Fprintf(text, "%s}\n", indent)
+		case field.wireType == protowire.BytesType:
+			fmt.Fprintf(text, "%s%d: %s\n", indent, field.number, quoteRawBytes(field.bytes))
+		default:
+			fmt.Fprintf(text, "%s%d: %d", indent, field.number, field.value)
+			if readings := alternativeReadings(field); len(readings) != 0 {
+				fmt.Fprintf(text, "  # %s", strings.Join(readings, ", "))
+			}
+			text.WriteString("\n")
+		}
+	}
+}
+
+// The readings of the value as the other types of the same wire type, which differ from the unsigned integer.
+func alternativeReadings(field rawField) []string {
+	var readings []string
+	switch field.wireType {
+	case protowire.VarintType:
+		if field.value > math.MaxInt64 {
+			readings = append(readings, fmt.Sprintf("signed: %d", int64(field.value)))
+		}
+		if field.value != 0 {
+			readings = append(readings, fmt.Sprintf("zigzag: %d", protowire.DecodeZigZag(field.value)))
+		}
+	case protowire.Fixed32Type:
+		if field.value > math.MaxInt32 {
+			readings = append(readings, fmt.Sprintf("signed: %d", int32(field.value)))
+		}
+		readings = append(readings, "float: "+strconv.FormatFloat(float64(math.Float32frombits(uint32(field.value))), 'g', -1, 32))
+	case protowire.Fixed64Type:
+		if field.value > math.MaxInt64 {
+			readings = append(readings, fmt.Sprintf("signed: %d", int64(field.value)))
+		}
+		readings = append(readings, "double: "+strconv.FormatFloat(math.Float64frombits(field.value), 'g', -1, 64))
+	}
+	return readings
+}
+
+func isPrintableText(value []byte) bool {
+	if !utf8.Valid(value) {
+		return false
+	}
+	for _, r := range string(value) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid UTF-8 is shown as it is. Otherwise, the bytes outside of printable ASCII are escaped.
+func quoteRawBytes(value []byte) string {
+	if utf8.Valid(value) {
+		return strconv.Quote(string(value))
+	}
+
+	var quoted strings.Builder
+	quoted.WriteString("\"")
+	for _, b := range value {
+		if b >= 0x20 && b < 0x7f && b != '"' && b != '\\' {
+			quoted.WriteByte(b)
+		} else {
+			fmt.Fprintf(&quoted, "\\x%02x", b)
+		}
+	}
+	quoted.WriteString("\"")
+	return quoted.String()
+}
+
+// The field numbers are kept in the order of their first occurrence.
+func formatRawFieldsAsJson(fields []rawField) []byte {
+	var numbers []protowire.Number
+	values := map[protowire.Number][][]byte{}
+	for _, field := range fields {
+		if _, exists := values[field.number]; !exists {
+			numbers = append(numbers, field.number)
+		}
+		values[field.number] = append(values[field.number], formatRawValueAsJson(field))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, number := range numbers {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		fmt.Fprintf(&out, "\"%d\":", number)
+		if len(values[number]) == 1 {
+			out.Write(values[number][0])
+		} else {
+			out.WriteString("[")
+			out.Write(bytes.Join(values[number], []byte(",")))
+			out.WriteString("]")
+		}
+	}
+	out.WriteString("}")
+	return out.Bytes()
+}
+
+func formatRawValueAsJson(field rawField) []byte {
+	switch {
+	case field.isMessage():
+		return formatRawFieldsAsJson(field.message)
+	case field.wireType == protowire.BytesType && utf8.Valid(field.bytes):
+		return jsonString(string(field.bytes))
+	case field.wireType == protowire.BytesType:
+		return jsonString(base64.StdEncoding.EncodeToString(field.bytes))
+	default:
+		return []byte(strconv.FormatUint(field.value, 10))
+	}
+}
+
+func jsonString(value string) []byte {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value) // strings can always be encoded
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
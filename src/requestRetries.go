@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strconv"
+	"time"
+)
+
+/*
+Each request is attempted up to --max-attempts times. An attempt is retried, if it failed with a network error
+listed in --retry-errors or if the response has a status code listed in --retry-status. The delay between
+the attempts starts with --retry-delay and doubles after each retry.
+
+The timeouts --connect-timeout and --max-time apply to each attempt. They are passed on to curl
+and are set on the dialer and the client of the internal http implementation respectively.
+
+See:
+	https://curl.se/libcurl/c/libcurl-errors.html
+*/
+
+const (
+	RetryOnConnect = "connect"
+	RetryOnTimeout = "timeout"
+)
+
+var retryableErrorKinds = []string{RetryOnConnect, RetryOnTimeout}
+
+// An attempt returns the response body and headers. The error is only returned, if no response was received.
+type requestAttempt func() ([]byte, string, error)
+
+// Invokes the attempt until it succeeds or is not retryable anymore. The failureMessage is used for the final network error.
+// The status code of the returned response still needs to be checked via ensureStatusCodeIsAccepted.
+func invokeWithRetries(attempt requestAttempt, errorKind func(error) string, failureMessage string) ([]byte, string) {
+	delay := secondsToDuration(CurrentConfig.RetryDelaySeconds)
+
+	for attemptNumber := 1; ; attemptNumber++ {
+		if CurrentConfig.Verbose && CurrentConfig.MaxAttempts > 1 {
+			fmt.Printf("Attempt %d of %d.\n", attemptNumber, CurrentConfig.MaxAttempts)
+		}
+
+		body, headers, err := attempt()
+		isLastAttempt := attemptNumber >= CurrentConfig.MaxAttempts
+
+		var failure string
+		if err != nil {
+			kind := errorKind(err)
+			if isLastAttempt || !slices.Contains(CurrentConfig.RetryErrors, kind) {
+				PanicWithMessageOnError(err, func() string { return failureMessage + " Error: " + err.Error() })
+			}
+			failure = fmt.Sprintf("Encountered %s error: %s", kind, err.Error())
+		} else {
+			statusCode := statusCodeOfHeaders(headers)
+			if isLastAttempt || !isRetryableStatusCode(statusCode) {
+				return body, headers
+			}
+			failure = fmt.Sprintf("Received retryable response status code %d", statusCode)
+		}
+
+		if CurrentConfig.Verbose {
+			fmt.Printf("Attempt %d failed. %s. Retrying in %s.\n", attemptNumber, failure, delay)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// An accepted or explicitly expected status code is never retried.
+func isRetryableStatusCode(statusCode int) bool {
+	return !isStatusCodeAccepted(statusCode) && slices.Contains(CurrentConfig.RetryStatusCodes, statusCode)
+}
+
+// Returns 0, if the status code could not be found in the first line of the headers.
+func statusCodeOfHeaders(headers string) int {
+	matches := httpStatusCode.FindStringSubmatch(headers)
+	if matches == nil {
+		return 0
+	}
+	statusCode, _ := strconv.Atoi(matches[1])
+	return statusCode
+}
+
+func internalRequestErrorKind(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return RetryOnTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return RetryOnConnect
+	}
+
+	return ""
+}
+
+func curlErrorKind(err error) string {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return ""
+	}
+
+	switch exitErr.ExitCode() {
+	case 6, 7: // could not resolve host, failed to connect
+		return RetryOnConnect
+	case 28: // operation timeout
+		return RetryOnTimeout
+	default:
+		return ""
+	}
+}
+
+func clientWithTimeouts(transport *http.Transport) *http.Client {
+	transport.DialContext = (&net.Dialer{
+		Timeout:   secondsToDuration(CurrentConfig.ConnectTimeoutSeconds),
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	return &http.Client{Transport: transport, Timeout: secondsToDuration(CurrentConfig.MaxTimeSeconds)}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+The response can be checked against expectations given via --expect, --expect-status and --expect-header.
+The decoded response is compared field by field with the expected message via the MessageDiffer. All differences
+are collected and reported together, such that protocurl can be used as a runner for contract tests.
+
+With --expect-partial, only the fields set in the expected message are compared.
+The expected message has the response type. A response decoded as google.rpc.Status instead does not meet it.
+
+See:
+	https://pkg.go.dev/google.golang.org/protobuf/reflect/protoreflect#Message
+*/
+
+var httpStatusCode = regexp.MustCompile(`^HTTP/\S+ ([0-9]{3})`)
+
+func hasResponseExpectations() bool {
+	return CurrentConfig.ExpectedResponse != "" || len(CurrentConfig.ExpectedHeaders) != 0
+}
+
+func ensureStatusCodeIsExpected(httpStatusLine string) {
+	matches := httpStatusCode.FindStringSubmatch(httpStatusLine)
+	if matches == nil {
+		PanicWithMessage("Could not find the status code in the response status line. Got: " + httpStatusLine)
+	}
+
+	statusCode, err := strconv.Atoi(matches[1])
+	PanicOnError(err)
+
+	if statusCode != CurrentConfig.ExpectedStatusCode {
+		PanicDueToHttpStatus(fmt.Sprintf("Received response status code %d instead of the expected %d. Got: %s",
+			statusCode, CurrentConfig.ExpectedStatusCode, httpStatusLine))
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Received expected response status code %d.\n", statusCode)
+	}
+}
+
+func ensureResponseMeetsExpectations(responseMsg *dynamicpb.Message, responseHeaders string, registry *protoregistry.Files) {
+	var differences []string
+
+	differences = append(differences, collectHeaderDifferences(responseHeaders)...)
+
+	if CurrentConfig.ExpectedResponse != "" {
+		// The response may have been decoded as google.rpc.Status due to --status-content-type instead.
+		expectedMsg := expectedResponseMessage(*resolveMessageByName(CurrentConfig.ResponseType, registry), registry)
+		if expectedMsg.Descriptor().FullName() != responseMsg.Descriptor().FullName() {
+			differences = append(differences, fmt.Sprintf("response: expected a %s, got a %s",
+				expectedMsg.Descriptor().FullName(), responseMsg.Descriptor().FullName()))
+		} else {
+			differ := MessageDiffer{OnlyFieldsSetInLeft: CurrentConfig.ExpectPartialMatch}
+			for _, difference := range differ.Compare(expectedMsg, responseMsg) {
+				differences = append(differences, fmt.Sprintf("%s: expected %s, got %s", difference.Path, difference.Left, difference.Right))
+			}
+		}
+	}
+
+	if len(differences) != 0 {
+		PanicWithMessage("The response does not meet the expectations:\n  " + strings.Join(differences, "\n  "))
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Println("The response meets all expectations.")
+	}
+}
+
+func expectedResponseMessage(descriptor protoreflect.MessageDescriptor, registry *protoregistry.Files) *dynamicpb.Message {
+	expectedMsg := dynamicpb.NewMessage(descriptor)
+
+	resolver := newRegistryTypeResolver(registry)
+
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(CurrentConfig.ExpectedResponse), "{") {
+		err = protojson.UnmarshalOptions{Resolver: resolver}.Unmarshal([]byte(CurrentConfig.ExpectedResponse), expectedMsg)
+	} else {
+		err = prototext.UnmarshalOptions{Resolver: resolver}.Unmarshal([]byte(CurrentConfig.ExpectedResponse), expectedMsg)
+	}
+	PanicWithMessageOnError(err, func() string {
+		return "Could not parse the expected response (--expect) as " + string(descriptor.FullName()) + "."
+	})
+
+	return expectedMsg
+}
+
+// Returns the values of the response headers by their lower case names.
+func parseResponseHeaders(responseHeaders string) map[string][]string {
+	receivedHeaders := make(map[string][]string)
+	for _, line := range strings.Split(responseHeaders, "\n")[1:] { // skip status line
+		if name, value, found := strings.Cut(line, ":"); found {
+			lowerName := strings.ToLower(strings.TrimSpace(name))
+			receivedHeaders[lowerName] = append(receivedHeaders[lowerName], strings.TrimSpace(value))
+		}
+	}
+	return receivedHeaders
+}
+
+func collectHeaderDifferences(responseHeaders string) (differences []string) {
+	receivedHeaders := parseResponseHeaders(responseHeaders)
+
+	for _, expectedHeader := range CurrentConfig.ExpectedHeaders {
+		name, value := splitHeader(expectedHeader)
+		values, found := receivedHeaders[strings.ToLower(name)]
+		if !found {
+			differences = append(differences, fmt.Sprintf("header %s: expected %q, got %s", name, value, unsetFieldValue))
+			continue
+		}
+
+		matched := false
+		for _, receivedValue := range values {
+			matched = matched || receivedValue == value
+		}
+		if !matched {
+			differences = append(differences, fmt.Sprintf("header %s: expected %q, got %q", name, value, strings.Join(values, ", ")))
+		}
+	}
+	return
+}
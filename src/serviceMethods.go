@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+Methods of services declare their request and response types. Hence, when a method is given via --rpc,
+then -i and -o can be inferred from it. If the method is additionally annotated with google.api.http,
+then the http method and the url path are taken from the annotation as well. The variables in the path template
+(e.g. /v1/days/{date.seconds}) are filled with the corresponding fields of the request.
+The set fields, which are neither bound by the path template nor sent in the body, are sent as query parameters.
+Only the primary binding is used. The additional_bindings of the annotation are ignored.
+
+See:
+	https://github.com/googleapis/googleapis/blob/master/google/api/http.proto
+	https://pkg.go.dev/google.golang.org/protobuf/reflect/protoreflect#MethodDescriptor
+*/
+
+const googleApiHttpExtensionFieldNumber protowire.Number = 72295728
+const googleApiHttpRuleMessageType = "google.api.HttpRule"
+
+type HttpRule struct {
+	Method             string
+	PathTemplate       string
+	Body               string
+	AdditionalBindings int
+}
+
+var httpRulePathVariable = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?}`)
+
+// set, if the method given via --rpc has a HTTP annotation
+var rpcHttpRule *HttpRule
+
+func resolveRpcMethodAndInferRequestDetails(registry *protoregistry.Files) {
+	method := resolveMethodByName(CurrentConfig.RpcMethod, registry)
+
+	inferRequestAndResponseTypesFromMethod(method)
+
+	rule, found := findHttpRule(method, registry)
+	if !found {
+		if CurrentConfig.Verbose {
+			fmt.Printf("Method %s has no google.api.http annotation. Using -X and -u as provided.\n", method.FullName())
+		}
+		return
+	}
+	rpcHttpRule = &rule
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Found google.api.http annotation on method %s: %s %s (body: '%s')\n", method.FullName(), rule.Method, rule.PathTemplate, rule.Body)
+		if rule.AdditionalBindings != 0 {
+			fmt.Printf("Ignoring the %d additional bindings of the google.api.http annotation. Only the primary binding is used.\n", rule.AdditionalBindings)
+		}
+	}
+
+	if tmpMethodExplicitlyProvided {
+		if CurrentConfig.Verbose {
+			fmt.Printf("Keeping explicitly provided method %s instead of %s.\n", CurrentConfig.Method, rule.Method)
+		}
+	} else {
+		CurrentConfig.Method = rule.Method
+	}
+}
+
+func inferRequestAndResponseTypesFromMethod(method protoreflect.MethodDescriptor) {
+	if CurrentConfig.RequestType == "" {
+		CurrentConfig.RequestType = string(method.Input().FullName())
+		if CurrentConfig.Verbose {
+			fmt.Printf("Inferred request type %s from method %s.\n", CurrentConfig.RequestType, method.FullName())
+		}
+	}
+
+	if CurrentConfig.ResponseType == "" {
+		CurrentConfig.ResponseType = string(method.Output().FullName())
+		CurrentConfig.DecodeRawResponse = false
+		if CurrentConfig.Verbose {
+			fmt.Printf("Inferred response type %s from method %s.\n", CurrentConfig.ResponseType, method.FullName())
+		}
+	}
+}
+
+// The google.api.http option is an extension unknown to protocurl itself. Hence, we look for its field in the
+// serialised method options and decode it with the google.api.HttpRule message type from the registry.
+func findHttpRule(method protoreflect.MethodDescriptor, registry *protoregistry.Files) (HttpRule, bool) {
+	optionsBinary, err := proto.Marshal(method.Options())
+	PanicOnError(err)
+
+	var ruleBinary []byte
+	for len(optionsBinary) > 0 {
+		number, wireType, tagLength := protowire.ConsumeTag(optionsBinary)
+		PanicOnError(protowire.ParseError(tagLength))
+		valueLength := protowire.ConsumeFieldValue(number, wireType, optionsBinary[tagLength:])
+		PanicOnError(protowire.ParseError(valueLength))
+
+		if number == googleApiHttpExtensionFieldNumber && wireType == protowire.BytesType {
+			ruleBinary, _ = protowire.ConsumeBytes(optionsBinary[tagLength:])
+		}
+		optionsBinary = optionsBinary[tagLength+valueLength:]
+	}
+
+	if ruleBinary == nil {
+		return HttpRule{}, false
+	}
+
+	ruleMsg := dynamicpb.NewMessage(*resolveMessageByName(googleApiHttpRuleMessageType, registry))
+	PanicOnError(proto.Unmarshal(ruleBinary, ruleMsg))
+
+	fields := ruleMsg.Descriptor().Fields()
+	patternField := ruleMsg.WhichOneof(ruleMsg.Descriptor().Oneofs().ByName("pattern"))
+	if patternField == nil {
+		PanicWithMessage("The google.api.http annotation of method " + string(method.FullName()) + " has no http method and path.")
+	}
+
+	rule := HttpRule{
+		Body:               ruleMsg.Get(fields.ByName("body")).String(),
+		AdditionalBindings: ruleMsg.Get(fields.ByName("additional_bindings")).List().Len(),
+	}
+	if patternField.Name() == "custom" {
+		custom := ruleMsg.Get(patternField).Message()
+		rule.Method = custom.Get(custom.Descriptor().Fields().ByName("kind")).String()
+		rule.PathTemplate = custom.Get(custom.Descriptor().Fields().ByName("path")).String()
+	} else {
+		rule.Method = strings.ToUpper(string(patternField.Name()))
+		rule.PathTemplate = ruleMsg.Get(patternField).String()
+	}
+
+	return rule, true
+}
+
+// Returns the url with the filled path template and the query parameters as well as the request body according to the body field of the HttpRule.
+func applyHttpRule(rule HttpRule, requestBinary []byte, registry *protoregistry.Files) (string, []byte) {
+	requestMsg := dynamicpb.NewMessage(*resolveMessageByName(CurrentConfig.RequestType, registry))
+	PanicOnError(proto.Unmarshal(requestBinary, requestMsg))
+
+	var boundFieldPaths []string
+	path := httpRulePathVariable.ReplaceAllStringFunc(rule.PathTemplate, func(variable string) string {
+		submatches := httpRulePathVariable.FindStringSubmatch(variable)
+		boundFieldPaths = append(boundFieldPaths, submatches[1])
+		value := formatFieldValueAtPath(requestMsg, submatches[1])
+		if submatches[2] == "" {
+			return url.PathEscape(value) // single path segment
+		}
+		return value // multiple path segments, e.g. {name=shelves/*}
+	})
+
+	requestUrl := strings.TrimSuffix(CurrentConfig.Url, "/") + path
+	if rule.Body != "*" {
+		query := url.Values{}
+		addQueryParameters(query, requestMsg, "", append(boundFieldPaths, rule.Body))
+		if len(query) != 0 {
+			requestUrl += "?" + query.Encode()
+		}
+	}
+	if CurrentConfig.Verbose {
+		fmt.Printf("Using url %s from google.api.http annotation.\n", requestUrl)
+	}
+
+	switch rule.Body {
+	case "*":
+		return requestUrl, requestBinary
+	case "":
+		if CurrentConfig.Verbose {
+			fmt.Println("The google.api.http annotation declares no body. Hence, an empty body will be sent.")
+		}
+		return requestUrl, []byte{}
+	default:
+		bodyField := requestMsg.Descriptor().Fields().ByName(protoreflect.Name(rule.Body))
+		if bodyField == nil || bodyField.Message() == nil || bodyField.IsList() || bodyField.IsMap() {
+			PanicWithMessage("The body '" + rule.Body + "' of the google.api.http annotation is not a message field of " + CurrentConfig.RequestType + ".")
+		}
+		bodyBinary, err := binaryMarshalOptions.Marshal(requestMsg.Get(bodyField).Message().Interface())
+		PanicOnError(err)
+		return requestUrl, bodyBinary
+	}
+}
+
+// Nested messages are flattened into dotted parameter names (e.g. date.seconds) and repeated fields into repeated parameters.
+// Maps and repeated messages cannot be represented as query parameters.
+func addQueryParameters(query url.Values, msg protoreflect.Message, prefix string, excludedFieldPaths []string) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		fieldPath := prefix + string(field.Name())
+		if !msg.Has(field) || slices.Contains(excludedFieldPaths, fieldPath) {
+			continue
+		}
+
+		switch {
+		case field.IsMap() || (field.IsList() && field.Message() != nil):
+			PanicWithMessage("The field " + fieldPath + " is neither bound by the path nor sent in the body of the google.api.http annotation. " +
+				"Since it is a map or a repeated message, it cannot be sent as a query parameter.")
+		case field.IsList():
+			elements := msg.Get(field).List()
+			for j := 0; j < elements.Len(); j++ {
+				query.Add(fieldPath, formatQueryParameterValue(field, elements.Get(j)))
+			}
+		case field.Message() != nil:
+			addQueryParameters(query, msg.Get(field).Message(), fieldPath+".", excludedFieldPaths)
+		default:
+			query.Add(fieldPath, formatQueryParameterValue(field, msg.Get(field)))
+		}
+	}
+}
+
+func formatQueryParameterValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+	case protoreflect.BytesKind:
+		return base64.URLEncoding.EncodeToString(value.Bytes())
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// The message type of the request body after applying the google.api.http annotation. It is empty, if no body is sent.
+func requestBodyType(registry *protoregistry.Files) string {
+	if rpcHttpRule == nil || rpcHttpRule.Body == "*" {
+		return CurrentConfig.RequestType
+	}
+	if rpcHttpRule.Body == "" {
+		return ""
+	}
+	requestDescriptor := *resolveMessageByName(CurrentConfig.RequestType, registry)
+	return string(requestDescriptor.Fields().ByName(protoreflect.Name(rpcHttpRule.Body)).Message().FullName())
+}
+
+func formatFieldValueAtPath(msg protoreflect.Message, fieldPath string) string {
+	fieldNames := strings.Split(fieldPath, ".")
+	for i, fieldName := range fieldNames {
+		field := msg.Descriptor().Fields().ByName(protoreflect.Name(fieldName))
+		if field == nil || field.IsList() || field.IsMap() {
+			PanicWithMessage("The path variable " + fieldPath + " of the google.api.http annotation does not refer to a singular field of " + string(msg.Descriptor().FullName()) + ".")
+		}
+
+		value := msg.Get(field)
+		if i < len(fieldNames)-1 {
+			if field.Message() == nil {
+				PanicWithMessage("The path variable " + fieldPath + " of the google.api.http annotation traverses the non-message field " + fieldName + ".")
+			}
+			msg = value.Message()
+			continue
+		}
+
+		if field.Enum() != nil {
+			if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+				return string(enumValue.Name())
+			}
+		}
+		return fmt.Sprint(value.Interface())
+	}
+	return ""
+}
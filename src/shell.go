@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/peterh/liner"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+/*
+The shell subcommand converts the .proto files to a proto registry once and then reads requests line by line.
+Each line accepts the same flags as protocurl itself (except for the ones determining the proto registry).
+All flags except for -d and the --expect flags are remembered for the subsequent lines. Headers given via -H are accumulated and
+a url starting with '/' is appended to the previously given base url. Remembered headers are removed via the builtin commands
+unset-header <name> and reset-headers.
+
+See:
+	https://pkg.go.dev/github.com/peterh/liner
+*/
+
+const shellPrompt = "protocurl> "
+const shellHistoryFileName = ".protocurl_history"
+
+var shellBuiltinCommands = []string{"exit", "help", "quit", "reset-headers", "show", "unset-header <name>"}
+
+// These flags determine the proto registry. Hence, they can only be provided when starting the shell.
+var shellRegistryFlags = []string{"proto-dir", "proto-file", "infer-files", "protoc", "protoc-path", "no-protoc", "descriptor-set"}
+
+type ShellSession struct {
+	Config         Config
+	BaseUrl        string
+	InTextType     string
+	OutTextType    string
+	RequestHeaders []string
+	registry       *protoregistry.Files
+}
+
+var shellCmd = &cobra.Command{
+	Short: "Starts an interactive session which reuses the converted .proto files for multiple requests.",
+	Use: "shell [flags]\n\n" +
+		"The .proto files are converted once at the start. Afterwards, each line accepts the flags of protocurl, e.g. -i ..MyRequest -d \"myField: true\".\n" +
+		"All flags except -d and the --expect flags are remembered for the subsequent lines. Headers (-H) are accumulated and a url (-u) starting with '/' is appended to the base url.\n" +
+		"Remembered headers are removed via unset-header <name> and reset-headers.\n" +
+		"The flags -I, -f, -F, --protoc, --protoc-path, --no-protoc and --descriptor-set can only be provided when starting the shell.\n" +
+		"Tab completes flags, message names, method names and the fields of the current request type. The history is kept in ~/" + shellHistoryFileName + ".\n" +
+		"Builtin commands: " + strings.Join(shellBuiltinCommands, ", "),
+	Example:               "  protocurl shell -I my-protos -u http://example.com/api",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		registry := convertProtoFilesToProtoRegistryFilesWithRawFormat()
+
+		session := ShellSession{
+			Config:         CurrentConfig,
+			BaseUrl:        CurrentConfig.Url,
+			InTextType:     tmpInTextType,
+			OutTextType:    tmpOutTextType,
+			RequestHeaders: CurrentConfig.RequestHeaders,
+			registry:       registry,
+		}
+
+		runShell(&session)
+	},
+}
+
+func initialiseShellCommand() {
+	addFlags(shellCmd.Flags())
+	rootCmd.AddCommand(shellCmd)
+}
+
+func runShell(session *ShellSession) {
+	line := liner.NewLiner()
+	defer func() { _ = line.Close() }()
+
+	line.SetCtrlCAborts(true)
+	line.SetWordCompleter(session.completeWord)
+
+	historyPath := shellHistoryPath()
+	if historyFile, err := os.Open(historyPath); err == nil {
+		_, _ = line.ReadHistory(historyFile)
+		_ = historyFile.Close()
+	}
+	defer func() {
+		if historyFile, err := os.Create(historyPath); err == nil {
+			_, _ = line.WriteHistory(historyFile)
+			_ = historyFile.Close()
+		}
+	}()
+
+	for {
+		input, err := line.Prompt(shellPrompt)
+		if errors.Is(err, io.EOF) || errors.Is(err, liner.ErrPromptAborted) {
+			fmt.Println()
+			return
+		}
+		PanicOnError(err)
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		line.AppendHistory(input)
+
+		switch input {
+		case "exit", "quit":
+			return
+		case "help":
+			printShellHelp()
+			continue
+		case "show":
+			printAsJson(*session)
+			continue
+		case "reset-headers":
+			session.setRequestHeaders([]string{})
+			continue
+		}
+		if headerName, found := strings.CutPrefix(input, "unset-header "); found {
+			session.unsetHeader(strings.TrimSpace(headerName))
+			continue
+		}
+
+		session.runLine(input)
+	}
+}
+
+func shellHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return shellHistoryFileName
+	}
+	return filepath.Join(home, shellHistoryFileName)
+}
+
+func printShellHelp() {
+	fmt.Println("Provide the flags of a request, e.g. -i ..MyRequest -d \"myField: true\".\n" +
+		"All flags except -d and the --expect flags are remembered for the subsequent lines. Use an empty value (e.g. -o '') to reset a flag.\n" +
+		"Headers (-H) are accumulated. Use unset-header <name> to remove a header and reset-headers to remove all of them.\n" +
+		"Builtin commands: " + strings.Join(shellBuiltinCommands, ", ") + "\n\nFlags:")
+	fmt.Print(newShellLineFlags().FlagUsages())
+}
+
+// Creates the flags for a line. This resets CurrentConfig to the flag defaults.
+func newShellLineFlags() *pflag.FlagSet {
+	lineFlags := pflag.NewFlagSet("shell", pflag.ContinueOnError)
+	lineFlags.SortFlags = false
+	lineFlags.SetOutput(io.Discard)
+	addFlags(lineFlags)
+	return lineFlags
+}
+
+// Removes the remembered headers with the given name. The name is case-insensitive.
+func (session *ShellSession) unsetHeader(name string) {
+	var remaining []string
+	for _, header := range session.RequestHeaders {
+		headerName, _, _ := strings.Cut(header, ":")
+		if !strings.EqualFold(strings.TrimSpace(headerName), name) {
+			remaining = append(remaining, header)
+		}
+	}
+
+	if len(remaining) == len(session.RequestHeaders) {
+		PrintError(errors.New("No header " + name + " is remembered."))
+		return
+	}
+	session.setRequestHeaders(remaining)
+}
+
+func (session *ShellSession) setRequestHeaders(headers []string) {
+	session.RequestHeaders = append([]string{}, headers...)
+	session.Config.RequestHeaders = append([]string{}, headers...)
+
+	if len(headers) == 0 {
+		fmt.Println("No headers are remembered.")
+		return
+	}
+	fmt.Println("Remembered headers:\n  " + strings.Join(headers, "\n  "))
+}
+
+// Runs a single request. Errors are printed and the shell continues afterwards.
+func (session *ShellSession) runLine(input string) {
+	defer func() {
+		if err := recover(); err != nil {
+			PrintError(fmt.Errorf("%v", err))
+		}
+	}()
+
+	args, err := shellquote.Split(input)
+	PanicWithMessageOnError(err, func() string { return "Could not split the line into arguments." })
+
+	lineFlags := newShellLineFlags()
+	CurrentConfig = session.Config
+	CurrentConfig.RequestHeaders = []string{}
+	tmpInTextType = session.InTextType
+	tmpOutTextType = session.OutTextType
+
+	PanicOnError(lineFlags.Parse(args))
+
+	if lineFlags.NArg() != 0 {
+		PanicWithMessage(fmt.Sprintf("Unexpected arguments %q. Please provide flags only.", lineFlags.Args()))
+	}
+
+	for _, registryFlag := range shellRegistryFlags {
+		if lineFlags.Changed(registryFlag) {
+			PanicWithMessage("The flag --" + registryFlag + " can only be provided when starting the shell.")
+		}
+	}
+
+	if CurrentConfig.DataText == "@-" {
+		PanicWithMessage("Reading the payload from stdin via -d @- is not supported in the shell, since stdin provides the commands.")
+	}
+
+	if lineFlags.Changed("url") {
+		if strings.HasPrefix(CurrentConfig.Url, "/") {
+			CurrentConfig.Url = strings.TrimSuffix(session.BaseUrl, "/") + CurrentConfig.Url
+		} else {
+			session.BaseUrl = CurrentConfig.Url
+		}
+	}
+
+	session.RequestHeaders = append(session.RequestHeaders, CurrentConfig.RequestHeaders...)
+	CurrentConfig.RequestHeaders = append([]string{}, session.RequestHeaders...)
+
+	session.Config = CurrentConfig
+	session.Config.DataText = ""
+	session.Config.ExpectedResponse = ""
+	session.Config.ExpectPartialMatch = false
+	session.Config.ExpectedStatusCode = 0
+	session.Config.ExpectedHeaders = []string{}
+	session.InTextType = tmpInTextType
+	session.OutTextType = tmpOutTextType
+
+	if CurrentConfig.Url == "" {
+		PanicWithMessage("No url was provided. Please provide it via -u <url>.")
+	}
+
+	tmpMethodExplicitlyProvided = lineFlags.Changed("method")
+
+	runSingleRequestWithRegistry(session.registry)
+}
+
+// Completes the word before the cursor depending on the previous word.
+func (session *ShellSession) completeWord(line string, pos int) (head string, completions []string, tail string) {
+	head, tail = line[:pos], line[pos:]
+
+	wordStart := strings.LastIndexAny(head, " \"'{") + 1
+	word := head[wordStart:]
+	head = head[:wordStart]
+	previousWords := strings.Fields(head)
+	previousWord := ""
+	if len(previousWords) != 0 {
+		previousWord = previousWords[len(previousWords)-1]
+	}
+
+	var candidates []string
+	switch {
+	case previousWord == "-i" || previousWord == "--request-type" || previousWord == "-o" || previousWord == "--response-type":
+		candidates = session.messageNameCandidates()
+	case previousWord == "--rpc" || previousWord == "--grpc":
+		candidates = session.methodNameCandidates()
+	case strings.HasPrefix(word, "-"):
+		candidates = shellFlagCandidates()
+	default:
+		candidates = session.fieldNameCandidates()
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			completions = append(completions, candidate)
+		}
+	}
+	return
+}
+
+func (session *ShellSession) messageNameCandidates() (candidates []string) {
+	baseNameCount := make(map[protoreflect.Name]int)
+	messages := collectAllMessageDescriptors(session.registry)
+	for _, message := range messages {
+		candidates = append(candidates, string(message.FullName()))
+		baseNameCount[message.Name()]++
+	}
+	for name, count := range baseNameCount {
+		if count == 1 {
+			candidates = append(candidates, inferredMessagePathPrefix+string(name))
+		}
+	}
+	sort.Strings(candidates)
+	return
+}
+
+func (session *ShellSession) methodNameCandidates() (candidates []string) {
+	for _, method := range collectAllMethodDescriptors(session.registry) {
+		candidates = append(candidates, string(method.Parent().FullName())+"/"+string(method.Name()))
+	}
+	return
+}
+
+func shellFlagCandidates() (candidates []string) {
+	newShellLineFlags().VisitAll(func(flag *pflag.Flag) {
+		candidates = append(candidates, "--"+flag.Name)
+		if flag.Shorthand != "" {
+			candidates = append(candidates, "-"+flag.Shorthand)
+		}
+	})
+	sort.Strings(candidates)
+	return
+}
+
+func (session *ShellSession) fieldNameCandidates() (candidates []string) {
+	requestType := session.Config.RequestType
+	if requestType == "" {
+		return
+	}
+
+	for _, message := range collectAllMessageDescriptors(session.registry) {
+		if string(message.FullName()) == requestType || inferredMessagePathPrefix+string(message.Name()) == requestType {
+			fields := message.Fields()
+			for i := 0; i < fields.Len(); i++ {
+				candidates = append(candidates, string(fields.Get(i).Name()))
+			}
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+The skeleton subcommand prints a template payload of a message type which can be filled in and used via -d.
+
+Every field is filled with its default value: Zero values, the first value of enums and the default values
+declared in proto2. Repeated fields and maps contain a single element. Of each oneof, the first alternative is filled.
+Nested messages are expanded up to the depth given via --depth, which also ends recursive message types.
+Repeated fields and maps of messages beyond the depth are left empty, since their elements could not be left unset.
+
+In the text format, the remaining alternatives of oneofs are shown as comments and messages beyond the depth as {}.
+Since JSON has no comments, it only contains the first alternative of oneofs and messages beyond the depth are null.
+Well-known types such as google.protobuf.Timestamp are shown in their JSON mapping.
+*/
+
+var skeletonMessageType string
+var skeletonDepth int
+
+var skeletonCmd = &cobra.Command{
+	Short: "Prints a template payload of a message type with all fields filled with default values.",
+	Use: "skeleton [flags] -t message-type\n\n" +
+		"The template can be adapted and used as the request via -d. Repeated fields and maps contain a single element.\n" +
+		"In the text format, the alternatives of oneofs besides the first one are shown as comments. JSON only contains the first alternative.",
+	Example:               "  protocurl skeleton -I my-protos -t ..MyRequest --out json:pretty",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		propagateOutputFlags()
+
+		printVersionInfoVerbose(cmd)
+
+		outTextType := conversionOutTextType()
+		if skeletonDepth < 0 {
+			PanicWithMessage(fmt.Sprintf("The depth must not be negative. Got: %d", skeletonDepth))
+		}
+
+		propagateProtoFileFlags()
+		registry := convertProtoFilesToProtoRegistryFiles()
+
+		msg := dynamicpb.NewMessage(*resolveMessageByName(skeletonMessageType, registry))
+		fillSkeleton(msg, skeletonDepth)
+
+		if outTextType == OText {
+			var text strings.Builder
+			writeSkeletonText(&text, msg, "")
+			fmt.Print(text.String())
+			return
+		}
+
+		jsonOpts := jsonDenseformatOptions // shallow copy
+		if outTextType == OJsonPretty {
+			jsonOpts = jsonPrettyformatOptions
+		}
+		jsonOpts.EmitUnpopulated = true
+		jsonOpts.Resolver = newRegistryTypeResolver(registry)
+		json, err := jsonOpts.Marshal(msg)
+		PanicOnError(err)
+		fmt.Println(strings.TrimSuffix(string(json), "\n"))
+	},
+}
+
+func initialiseSkeletonCommand() {
+	flags := skeletonCmd.Flags()
+	addProtoFileFlags(flags)
+	addVerboseFlag(flags)
+
+	flags.StringVarP(&skeletonMessageType, "type", "t", "",
+		"Mandatory: Message name or full package path of the Protobuf message type. The path can be shortened to '..', if the name of the message is unique.")
+	AssertSuccess(skeletonCmd.MarkFlagRequired("type"))
+
+	flags.IntVar(&skeletonDepth, "depth", 3,
+		"Expands nested messages up to the given `depth`. Deeper messages are left empty.")
+
+	flags.StringVar(&tmpOutTextType, "out", "",
+		"Produces the output in the specified format. 'text' (default) produces Protobuf text format. 'json' produces dense JSON and "+
+			"'json:pretty' produces pretty-printed JSON.")
+
+	rootCmd.AddCommand(skeletonCmd)
+}
+
+// Fields with implicit presence remain unpopulated when set to their zero values. They are written nonetheless.
+func fillSkeleton(msg protoreflect.Message, depth int) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !isFilledOneofAlternative(field) {
+			continue
+		}
+
+		switch {
+		case field.IsMap():
+			entries := msg.Mutable(field).Map()
+			if field.MapValue().Message() == nil {
+				entries.Set(field.MapKey().Default().MapKey(), field.MapValue().Default())
+			} else if depth > 0 {
+				value := entries.NewValue()
+				fillSkeleton(value.Message(), depth-1)
+				entries.Set(field.MapKey().Default().MapKey(), value)
+			}
+		case field.IsList():
+			elements := msg.Mutable(field).List()
+			if field.Enum() != nil {
+				elements.Append(protoreflect.ValueOfEnum(field.Enum().Values().Get(0).Number()))
+			} else if field.Message() == nil {
+				elements.Append(elements.NewElement()) // the default of repeated fields is invalid
+			} else if depth > 0 {
+				element := elements.NewElement()
+				fillSkeleton(element.Message(), depth-1)
+				elements.Append(element)
+			}
+		case field.Message() != nil:
+			if depth > 0 {
+				fillSkeleton(msg.Mutable(field).Message(), depth-1)
+			}
+		default:
+			msg.Set(field, field.Default())
+		}
+	}
+}
+
+func isFilledOneofAlternative(field protoreflect.FieldDescriptor) bool {
+	oneof := field.ContainingOneof()
+	return oneof == nil || oneof.IsSynthetic() || oneof.Fields().Get(0) == field
+}
+
+func writeSkeletonText(text *strings.Builder, msg protoreflect.Message, indent string) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if isFilledOneofAlternative(field) {
+			if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+				fmt.Fprintf(text, "%s# oneof %s: only one of the following fields can be set\n", indent, oneof.Name())
+			}
+			writeSkeletonField(text, msg, field, indent)
+		} else {
+			writeSkeletonOneofAlternative(text, field, indent)
+		}
+	}
+}
+
+func writeSkeletonField(text *strings.Builder, msg protoreflect.Message, field protoreflect.FieldDescriptor, indent string) {
+	name := field.TextName()
+	switch {
+	case field.IsMap():
+		entries := msg.Get(field).Map()
+		if entries.Len() == 0 {
+			fmt.Fprintf(text, "%s%s: []\n", indent, name)
+		}
+		entries.Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			fmt.Fprintf(text, "%s%s: {\n", indent, name)
+			fmt.Fprintf(text, "%s  key: %s\n", indent, formatSingularOptionValue(field.MapKey(), key.Value()))
+			if field.MapValue().Message() != nil {
+				writeSkeletonMessage(text, "value", value.Message(), indent+"  ")
+			} else {
+				fmt.Fprintf(text, "%s  value: %s\n", indent, formatSingularOptionValue(field.MapValue(), value))
+			}
+			fmt.Fprintf(text, "%s}\n", indent)
+			return true
+		})
+	case field.IsList():
+		elements := msg.Get(field).List()
+		if elements.Len() == 0 {
+			fmt.Fprintf(text, "%s%s: []\n", indent, name)
+		}
+		for i := 0; i < elements.Len(); i++ {
+			if field.Message() != nil {
+				writeSkeletonMessage(text, name, elements.Get(i).Message(), indent)
+			} else {
+				fmt.Fprintf(text, "%s%s: %s\n", indent, name, formatSingularOptionValue(field, elements.Get(i)))
+			}
+		}
+	case field.Message() != nil:
+		if msg.Has(field) {
+			writeSkeletonMessage(text, name, msg.Get(field).Message(), indent)
+		} else {
+			fmt.Fprintf(text, "%s%s: {}\n", indent, name)
+		}
+	default:
+		fmt.Fprintf(text, "%s%s: %s\n", indent, name, formatSingularOptionValue(field, msg.Get(field)))
+	}
+}
+
+func writeSkeletonMessage(text *strings.Builder, name string, msg protoreflect.Message, indent string) {
+	fmt.Fprintf(text, "%s%s: {\n", indent, name)
+	writeSkeletonText(text, msg, indent+"  ")
+	fmt.Fprintf(text, "%s}\n", indent)
+}
+
+func writeSkeletonOneofAlternative(text *strings.Builder, field protoreflect.FieldDescriptor, indent string) {
+	if field.Message() != nil {
+		fmt.Fprintf(text, "%s# %s: {}\n", indent, field.TextName())
+	} else {
+		fmt.Fprintf(text, "%s# %s: %s\n", indent, field.TextName(), formatSingularOptionValue(field, field.Default()))
+	}
+}
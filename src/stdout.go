@@ -28,7 +28,7 @@ func printArgsVerbose() {
 
 func printVersionInfoVerbose(cmd *cobra.Command) {
 	if CurrentConfig.Verbose {
-		fmt.Printf("protocurl %s\n", cmd.Version)
+		fmt.Printf("protocurl %s\n", cmd.Root().Version)
 	}
 }
 
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net/http/httputil"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+/*
+With --stream, the response of the internal http implementation is read as a stream of frames.
+Each frame is decoded and shown as soon as it arrives - which suits long-polling and server-sent events.
+
+The frames are either server-sent events whose data lines carry a base64 encoded message,
+lines of base64 encoded messages or messages prefixed by their varint-encoded length.
+
+See:
+	https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+*/
+
+const (
+	StreamSse         = "sse"
+	StreamBase64Lines = "base64-lines"
+	StreamDelimited   = "delimited"
+)
+
+var streamFormats = []string{StreamSse, StreamBase64Lines, StreamDelimited}
+
+// Returns the next frame or io.EOF, if the stream has ended.
+type frameReader func() ([]byte, error)
+
+// The response is decoded while it is received. Hence, it is not retried.
+func invokeStreamingHttpRequest(requestBinary []byte, registry *protoregistry.Files) {
+	if CurrentConfig.Verbose {
+		fmt.Printf("Invoking internal http request and reading the response as a stream of %s frames.\n", CurrentConfig.StreamFormat)
+	}
+
+	httpResponse, err := internalHttpClient().Do(newInternalHttpRequest(requestBinary))
+	PanicWithMessageOnError(err, func() string { return "Failed internal HTTP request. Error: " + err.Error() })
+	defer func() { _ = httpResponse.Body.Close() }()
+
+	headers, _ := httputil.DumpResponse(httpResponse, false)
+	responseHeaders := strings.TrimSpace(string(headers))
+
+	if !isStatusCodeAccepted(httpResponse.StatusCode) {
+		responseBody, _ := io.ReadAll(httpResponse.Body) // the status code is reported nonetheless
+		decodeErrorOrStatusResponse(decodeResponseWireFormat(responseBody, responseHeaders, registry), responseHeaders, registry)
+		ensureStatusCodeIsAccepted(responseHeaders)
+	}
+
+	displayResponseHeaders(responseHeaders)
+
+	responseType := properResponseTypeIfProvidedOrEmptyType()
+	readFrame := newFrameReader(CurrentConfig.StreamFormat, httpResponse.Body)
+
+	frameNumber := 0
+	for {
+		frame, err := readFrame()
+		if err == io.EOF {
+			break
+		}
+		frameNumber++
+		PanicWithMessageOnError(err, func() string {
+			return fmt.Sprintf("Could not read frame %d of the streamed response.", frameNumber)
+		})
+
+		if CurrentConfig.DisplayBinaryAndHttp && !CurrentConfig.SilentMode {
+			fmt.Printf("%s %s Response Frame %d Binary %s %s\n%s", VISUAL_SEPARATOR, CurrentConfig.Method, frameNumber, VISUAL_SEPARATOR, RECV, hex.Dump(frame))
+		}
+
+		frameText, _ := protoBinaryToMsgAndText(responseType, frame, CurrentConfig.OutTextType, registry)
+		displayDecodedResponse(fmt.Sprintf("Response Frame %d", frameNumber), CurrentConfig.OutTextType, frameText)
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Received %d frames.\n", frameNumber)
+	}
+
+	if hasResponseExpectations() {
+		ensureResponseMeetsExpectations(nil, responseHeaders, registry)
+	}
+}
+
+func newFrameReader(streamFormat string, body io.Reader) frameReader {
+	reader := bufio.NewReader(body)
+	switch streamFormat {
+	case StreamSse:
+		return func() ([]byte, error) { return readServerSentEventFrame(reader) }
+	case StreamBase64Lines:
+		return func() ([]byte, error) { return readBase64LineFrame(reader) }
+	default:
+		return func() ([]byte, error) { return readDelimitedFrame(reader) }
+	}
+}
+
+// The data lines of an event are joined. Other fields such as event, id and retry as well as comments are ignored.
+func readServerSentEventFrame(reader *bufio.Reader) ([]byte, error) {
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		if value, found := strings.CutPrefix(line, "data:"); found {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+
+		// an empty line or the end of the stream dispatches the event
+		if (line == "" || err != nil) && len(data) != 0 {
+			return base64.StdEncoding.DecodeString(strings.Join(data, ""))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Empty lines are skipped.
+func readBase64LineFrame(reader *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+
+		if line != "" {
+			return base64.StdEncoding.DecodeString(line)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readDelimitedFrame(reader *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(reader) // Protobuf varints are unsigned LEB128
+	if err != nil {
+		return nil, err
+	}
+
+	if length > math.MaxInt32 { // Protobuf messages are limited to 2 GiB
+		return nil, fmt.Errorf("the length prefix %d exceeds the maximum message size of %d bytes", length, math.MaxInt32)
+	}
+
+	// Only the received bytes are allocated, since the length prefix may be malformed.
+	frame, err := io.ReadAll(io.LimitReader(reader, int64(length)))
+	if err == nil && uint64(len(frame)) < length {
+		err = io.ErrUnexpectedEOF
+	}
+	return frame, err
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+Besides the Protobuf binary format, many services also accept and respond with the JSON mapping of Protobuf
+or the Protobuf text format. The wire format of the request is chosen via --wire-format and announced
+via the default Content-Type and Accept headers. The wire format of the response is chosen by its Content-Type.
+
+Internally, the request and response are always handled in the binary format. Hence, the request is converted
+into the wire format right before it is sent and the response is converted from it right after it was received.
+The input and output formats (--in, --out) are independent of the wire format.
+*/
+
+const (
+	WireBinary = "binary"
+	WireJson   = "json"
+	WireText   = "text"
+)
+
+var wireFormats = []string{WireBinary, WireJson, WireText}
+
+var wireFormatContentTypes = map[string]string{
+	WireBinary: DefaultContentType,
+	WireJson:   "application/json",
+	WireText:   "application/x-protobuf-text",
+}
+
+func ensureWireFormatIsKnown(format string) {
+	if !slices.Contains(wireFormats, format) {
+		PanicWithMessage(fmt.Sprintf("Unknown wire format %s. Expected %s, %s or %s for --wire-format", format, WireBinary, WireJson, WireText))
+	}
+}
+
+func wireFormatDefaultHeaders(format string) []string {
+	contentType := wireFormatContentTypes[format]
+	return []string{"Content-Type: " + contentType, "Accept: " + contentType}
+}
+
+// The response is in the binary wire format, unless its Content-Type is the one of JSON or the text format.
+// Structured JSON media types such as application/problem+json are treated as JSON as well.
+func wireFormatOfResponse(responseHeaders string) string {
+	for _, contentType := range parseResponseHeaders(responseHeaders)["content-type"] {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			continue
+		}
+		if mediaType == wireFormatContentTypes[WireJson] || strings.HasSuffix(mediaType, "+json") {
+			return WireJson
+		}
+		if mediaType == wireFormatContentTypes[WireText] {
+			return WireText
+		}
+	}
+	return WireBinary
+}
+
+// Without a request body type, no request body is sent. It is kept empty then.
+func encodeRequestWireFormat(requestBinary []byte, registry *protoregistry.Files) []byte {
+	if CurrentConfig.WireFormat == WireBinary {
+		return requestBinary
+	}
+	requestType := requestBodyType(registry)
+	if requestType == "" {
+		return requestBinary
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Sending the request in the %s wire format.\n", CurrentConfig.WireFormat)
+	}
+
+	requestText, _ := protoBinaryToMsgAndText(requestType, requestBinary, wireFormatOutTextType(), registry)
+	return []byte(requestText)
+}
+
+func wireFormatOutTextType() OutTextType {
+	if CurrentConfig.WireFormat == WireText {
+		return OText
+	}
+	return OJsonDense
+}
+
+// Converts the response into the binary format according to its Content-Type.
+// Successful responses are converted via the response type and failed responses via --error-type or as google.rpc.Status.
+// The bodies of failed responses are kept as they are, if they cannot be converted. They are usually plain error messages.
+// The bodies of successful responses are kept as they are without a response type. They are shown as received then.
+func decodeResponseWireFormat(responseBody []byte, responseHeaders string, registry *protoregistry.Files) []byte {
+	format := wireFormatOfResponse(responseHeaders)
+	if format == WireBinary || len(responseBody) == 0 {
+		return responseBody
+	}
+
+	if CurrentConfig.Verbose {
+		fmt.Printf("Decoding the response from the %s wire format due to its Content-Type.\n", format)
+	}
+
+	accepted := isStatusCodeAccepted(statusCodeOfHeaders(responseHeaders))
+
+	var descriptor protoreflect.MessageDescriptor
+	switch {
+	case accepted && CurrentConfig.ResponseType == "":
+		if CurrentConfig.Verbose {
+			fmt.Printf("Keeping the body of the response as it is, as no response type was provided.\n")
+		}
+		return responseBody
+	case accepted:
+		descriptor = *resolveMessageByName(CurrentConfig.ResponseType, registry)
+	case CurrentConfig.ErrorType != "":
+		descriptor = *resolveMessageByName(CurrentConfig.ErrorType, registry)
+	default:
+		statusDescriptor, found := googleRpcStatusDescriptor(registry)
+		if !found {
+			return responseBody
+		}
+		descriptor = statusDescriptor
+	}
+
+	responseBinary, err := wireToBinary(responseBody, format, descriptor, registry)
+	if err != nil && !accepted {
+		if CurrentConfig.Verbose {
+			fmt.Printf("Keeping the body of the unsuccessful response as it is, as it could not be decoded as %s.\n", descriptor.FullName())
+		}
+		return responseBody
+	}
+	PanicWithMessageOnError(err, func() string {
+		return "Could not decode the response from the " + format + " wire format as " + string(descriptor.FullName()) + "."
+	})
+	return responseBinary
+}
+
+// Without a response type, a successful response in the JSON or text wire format cannot be decoded raw.
+// Hence, it is shown as it was received instead.
+func isUntypedWireFormatResponse(responseBody []byte, responseHeaders string) bool {
+	return CurrentConfig.ResponseType == "" && len(responseBody) != 0 && wireFormatOfResponse(responseHeaders) != WireBinary
+}
+
+func displayWireFormatResponse(responseBody []byte, responseHeaders string) {
+	displayResponseHeadersAndBinary(responseBody, responseHeaders)
+
+	outFormat := OutTextType(OText)
+	responseText := strings.TrimSpace(string(responseBody))
+	if wireFormatOfResponse(responseHeaders) == WireJson {
+		outFormat = OJsonDense
+		var prettyResponse bytes.Buffer
+		if CurrentConfig.OutTextType == OJsonPretty && json.Indent(&prettyResponse, []byte(responseText), "", "  ") == nil {
+			responseText = prettyResponse.String()
+		}
+	}
+
+	displayDecodedResponse("Response", outFormat, responseText)
+}
+
+func wireToBinary(body []byte, format string, descriptor protoreflect.MessageDescriptor, registry *protoregistry.Files) ([]byte, error) {
+	msg := dynamicpb.NewMessage(descriptor)
+	resolver := newRegistryTypeResolver(registry)
+
+	var err error
+	if format == WireJson {
+		err = protojson.UnmarshalOptions{Resolver: resolver}.Unmarshal(body, msg)
+	} else {
+		err = prototext.UnmarshalOptions{Resolver: resolver}.Unmarshal(body, msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return binaryMarshalOptions.Marshal(msg)
+}
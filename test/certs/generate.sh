@@ -0,0 +1,20 @@
+#!/bin/bash
+set -euo pipefail
+# Generates the CA, server and client certificates used by the TLS testcases. They are valid for 100 years.
+# The server certificate is only valid for localhost, such that requests via 127.0.0.1 need --tls-server-name.
+
+cd "$(dirname "$0")"
+
+NEW_KEY="-newkey ec -pkeyopt ec_paramgen_curve:P-256 -nodes"
+
+openssl req -x509 $NEW_KEY -keyout ca-key.pem -out ca.pem -days 36500 -subj "/CN=protocurl test CA"
+
+openssl req $NEW_KEY -keyout server-key.pem -out server.csr -subj "/CN=localhost"
+openssl x509 -req -in server.csr -CA ca.pem -CAkey ca-key.pem -CAcreateserial -out server.pem -days 36500 \
+  -extfile <(printf "subjectAltName=DNS:localhost\nextendedKeyUsage=serverAuth\n")
+
+openssl req $NEW_KEY -keyout client-key.pem -out client.csr -subj "/CN=protocurl test client"
+openssl x509 -req -in client.csr -CA ca.pem -CAkey ca-key.pem -CAcreateserial -out client.pem -days 36500 \
+  -extfile <(printf "extendedKeyUsage=clientAuth\n")
+
+rm -f ca-key.pem ./*.csr ./*.srl
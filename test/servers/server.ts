@@ -1,16 +1,61 @@
 import protobuf from 'protobufjs';
+import fs from 'fs';
 import http from 'http';
+import http2 from 'http2';
+import https from 'https';
 import Long from 'long';
 
 const PORT = 8080;
+const GRPC_PORT = 8081;
+const HTTPS_PORT = 8443;
+const MTLS_PORT = 8444;
 
 const protoFilePath = 'proto/happyday.proto';
 const protoRequestPath = 'happyday.HappyDayRequest';
 const protoResponsePath = 'happyday.HappyDayResponse';
+const certsDirPath = 'certs';
 
 let ProtobufDefs: protobuf.Root;
 let HappyDayRequestType: protobuf.Type;
 let HappyDayResponseType: protobuf.Type;
+let MiscInfoType: protobuf.Type;
+
+/** google.rpc.Status and google.rpc.BadRequest would usually be loaded from the googleapis .proto files. */
+const GoogleRpcDefs = protobuf.Root.fromJSON({
+    nested: {
+        google: {
+            nested: {
+                protobuf: {
+                    nested: {
+                        Any: { fields: { type_url: { type: 'string', id: 1 }, value: { type: 'bytes', id: 2 } } }
+                    }
+                },
+                rpc: {
+                    nested: {
+                        Status: {
+                            fields: {
+                                code: { type: 'int32', id: 1 },
+                                message: { type: 'string', id: 2 },
+                                details: { rule: 'repeated', type: 'google.protobuf.Any', id: 3 }
+                            }
+                        },
+                        BadRequest: {
+                            fields: { field_violations: { rule: 'repeated', type: 'FieldViolation', id: 1 } },
+                            nested: {
+                                FieldViolation: {
+                                    fields: { field: { type: 'string', id: 1 }, description: { type: 'string', id: 2 } }
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    }
+});
+const GoogleRpcStatusType = GoogleRpcDefs.lookupType('google.rpc.Status');
+const GoogleRpcBadRequestType = GoogleRpcDefs.lookupType('google.rpc.BadRequest');
+const googleRpcStatusContentType = 'application/x-protobuf; proto=google.rpc.Status';
 
 const weekdays = ['Sunday', 'Monday', 'Tuesday', 'Wednesday', 'Thursday', 'Friday', 'Saturday'];
 const wednesdayDateWeekday = 3;
@@ -40,12 +85,27 @@ interface PathHandler {
     path: string;
     method: ('GET' | 'POST' | 'HEAD')[];
     reqType: protobuf.Type;
+    /** If true, then the request and response bodies are streams of varint length-delimited messages.
+     * The handler is invoked for each request message. */
+    delimited?: boolean;
 
-    handler(reqDecoded: { [p in string]: any }): Promise<[protobuf.Type, { [p in string]: any }]>;
+    handler(reqDecoded: { [p in string]: any }, url: URL): Promise<[protobuf.Type, { [p in string]: any }]>;
 }
 
+/** Handlers reject with this error to respond with the given status code and the optional body. */
+class HttpStatusError extends Error {
+    constructor(public statusCode: number, public body?: Uint8Array, public contentType = 'application/x-protobuf') {
+        super('Responding with status code ' + statusCode);
+    }
+}
+
+/** Number of failed requests of /flaky per url. */
+const flakyFailures = new Map<string, number>();
+
+const sleep = (millis: number) => new Promise(resolve => setTimeout(resolve, millis));
+
 /**
- * Defines two paths.
+ * Defines seven paths.
  *
  * <p>The path `/happy-day/verify` takes an HappyDayRequest and tells us, whether the
  * given date is a happy one. (Every day except Wednesday is defined to be happy, doh).
@@ -54,9 +114,21 @@ interface PathHandler {
  * is formatted to a string and additionally a "reason" is given, if requested.
  *
  * <p> The path `/echo` simply returns the input body back.
+ *
+ * <p> The paths `/flaky?failures=N` and `/slow?millis=N` behave like `/echo`. However, `/flaky` first responds
+ * N times with 503 and resets afterwards. `/slow` waits N milliseconds before responding.
+ *
+ * <p> The path `/error?status=N` responds with the status N and a HappyDayResponse containing the error in `err`.
+ *
+ * <p> The path `/rpc-status?code=C&status=N` responds with the status N (default 400) and a google.rpc.Status with the code C.
+ * Its details are a google.rpc.BadRequest and a happyday.MiscInfo. With `&unknownDetail`, a detail of an unknown type is added.
+ *
+ * <p> The path `/happy-day/verify-stream` behaves like `/happy-day/verify` for each message of a length-delimited stream.
+ *
+ * <p> Additionally, the path `/happy-day/stream` is served by streamHappyDays(...).
  */
 function defineHandlers(): PathHandler[] {
-    return [
+    const handlers: PathHandler[] = [
         {
             path: '/happy-day/verify',
             method: ['GET', 'POST', 'HEAD'],
@@ -102,8 +174,248 @@ function defineHandlers(): PathHandler[] {
             async handler(reqDecoded: { [p in string]: any }): Promise<[protobuf.Type, { [p in string]: any }]> {
                 return [HappyDayRequestType, reqDecoded];
             }
+        },
+        {
+            path: '/flaky',
+            method: ['GET', 'POST', 'HEAD'],
+            reqType: HappyDayRequestType,
+            async handler(reqDecoded: { [p in string]: any }, url: URL): Promise<[protobuf.Type, { [p in string]: any }]> {
+                const failures = flakyFailures.get(url.search) ?? 0;
+                if (failures < Number(url.searchParams.get('failures') ?? 0)) {
+                    flakyFailures.set(url.search, failures + 1);
+                    throw new HttpStatusError(503);
+                }
+                flakyFailures.delete(url.search);
+                return [HappyDayRequestType, reqDecoded];
+            }
+        },
+        {
+            path: '/slow',
+            method: ['GET', 'POST', 'HEAD'],
+            reqType: HappyDayRequestType,
+            async handler(reqDecoded: { [p in string]: any }, url: URL): Promise<[protobuf.Type, { [p in string]: any }]> {
+                await sleep(Number(url.searchParams.get('millis') ?? 0));
+                return [HappyDayRequestType, reqDecoded];
+            }
+        },
+        {
+            path: '/error',
+            method: ['GET', 'POST', 'HEAD'],
+            reqType: HappyDayRequestType,
+            async handler(reqDecoded: { [p in string]: any }, url: URL): Promise<[protobuf.Type, { [p in string]: any }]> {
+                const statusCode = Number(url.searchParams.get('status') ?? 500);
+                const error = { err: 'Failing with status ' + statusCode + ' as requested.' } as HappyDayResponse;
+                throw new HttpStatusError(statusCode, HappyDayResponseType.encode(error).finish());
+            }
+        },
+        {
+            path: '/rpc-status',
+            method: ['GET', 'POST', 'HEAD'],
+            reqType: HappyDayRequestType,
+            async handler(reqDecoded: { [p in string]: any }, url: URL): Promise<[protobuf.Type, { [p in string]: any }]> {
+                const code = Number(url.searchParams.get('code') ?? 3);
+                const statusCode = Number(url.searchParams.get('status') ?? 400);
+                const details = [
+                    {
+                        type_url: 'type.googleapis.com/google.rpc.BadRequest',
+                        value: GoogleRpcBadRequestType.encode({
+                            field_violations: [{ field: 'date', description: 'The date is required.' }]
+                        }).finish()
+                    },
+                    {
+                        type_url: 'type.googleapis.com/happyday.MiscInfo',
+                        value: MiscInfoType.encode({ weatherOfPastFewDays: ['rainy'] }).finish()
+                    }
+                ];
+                if (url.searchParams.has('unknownDetail')) {
+                    details.push({ type_url: 'type.googleapis.com/unknown.Detail', value: new Uint8Array([8, 1]) });
+                }
+                const status = { code, message: 'Failing with code ' + code + ' as requested.', details };
+                throw new HttpStatusError(statusCode, GoogleRpcStatusType.encode(status).finish(), googleRpcStatusContentType);
+            }
         }
     ];
+
+    const verifyHandler = handlers.find(handler => handler.path === '/happy-day/verify')!;
+    return [...handlers, { ...verifyHandler, path: '/happy-day/verify-stream', delimited: true }];
+}
+
+const streamFormats = ['sse', 'base64-lines', 'delimited'];
+
+/** Responds to `/happy-day/stream?format=F&frames=N&delayMillis=D` with N frames (default 3). Each of them is
+ * the response of `/happy-day/verify` for the requested date plus the number of preceding frames in days.
+ * The frames are sent one after another with a delay of D milliseconds (default 100) in the format F:
+ * `sse` sends server-sent events with base64 encoded data, `base64-lines` sends one base64 encoded message per line
+ * and `delimited` (default) sends varint length-delimited messages.
+ */
+async function streamHappyDays(verifyHandler: PathHandler, data: Buffer, url: URL, res: http.ServerResponse) {
+    const format = url.searchParams.get('format') ?? 'delimited';
+    const frames = Number(url.searchParams.get('frames') ?? 3);
+    const delayMillis = Number(url.searchParams.get('delayMillis') ?? 100);
+
+    if (!streamFormats.includes(format)) {
+        res.statusCode = 400;
+        res.end();
+        return;
+    }
+
+    const req = verifyHandler.reqType.decode(data) as unknown as HappyDayRequest;
+    const date = req.date ?? { seconds: new Long(0, 0), nanos: 0 };
+
+    res.statusCode = 200;
+    res.setHeader('Content-Type', format === 'sse' ? 'text/event-stream' : format === 'base64-lines' ? 'text/plain' : 'application/x-protobuf');
+
+    for (let i = 0; i < frames; i++) {
+        const dayReq = { ...req, date: { seconds: Long.fromValue(date.seconds).add(i * 24 * 60 * 60), nanos: date.nanos } };
+        const [responseType, respMessage] = await verifyHandler.handler(dayReq, url);
+        const encodedMsg = responseType.encode(respMessage).finish();
+        const base64 = Buffer.from(encodedMsg).toString('base64');
+
+        console.log('Streaming frame ' + i + ': ' + JSON.stringify(respMessage));
+        switch (format) {
+            case 'sse':
+                res.write(': frame ' + i + '\nevent: happy-day\nid: ' + i + '\ndata: ' + base64 + '\n\n');
+                break;
+            case 'base64-lines':
+                res.write(base64 + '\n');
+                break;
+            default:
+                res.write(responseType.encodeDelimited(respMessage).finish());
+        }
+        await sleep(delayMillis);
+    }
+
+    res.end();
+    console.log('=========== 200 OK');
+}
+
+/** The query parameter `bodyEncoding` of all paths defines the encoding of the request and the successful response bodies.
+ * `base64`, `base64url` and `hex` use the respective encoding. `base64-json` additionally wraps the response in a JSON string.
+ */
+function decodeBody(body: Buffer, bodyEncoding: string | null): Buffer {
+    switch (bodyEncoding) {
+        case 'base64':
+        case 'base64-json':
+            return Buffer.from(body.toString(), 'base64');
+        case 'base64url':
+            return Buffer.from(body.toString(), 'base64url');
+        case 'hex':
+            return Buffer.from(body.toString(), 'hex');
+        default:
+            return body;
+    }
+}
+
+function encodeBody(body: Uint8Array, bodyEncoding: string | null): Uint8Array | string {
+    switch (bodyEncoding) {
+        case 'base64':
+        case 'base64url':
+        case 'hex':
+            return Buffer.from(body).toString(bodyEncoding);
+        case 'base64-json':
+            return JSON.stringify(Buffer.from(body).toString('base64'));
+        default:
+            return body;
+    }
+}
+
+/** The JSON mapping of google.protobuf.Timestamp is an RFC 3339 string instead of an object with seconds and nanos. */
+protobuf.wrappers['.google.protobuf.Timestamp'] = {
+    fromObject(this: protobuf.Type, object: { [k: string]: any }): protobuf.Message {
+        if (typeof object === 'string') {
+            const millis = Date.parse(object);
+            return this.create({ seconds: Long.fromNumber(Math.floor(millis / 1000)), nanos: (millis % 1000) * 1000 * 1000 });
+        }
+        return this.fromObject(object);
+    },
+    toObject(this: protobuf.Type, message: protobuf.Message, options?: protobuf.IConversionOptions): { [k: string]: any } {
+        if (options?.json) {
+            const timestamp = message as unknown as { seconds: Long | number; nanos: number };
+            const millis = Long.fromValue(timestamp.seconds).toNumber() * 1000 + Math.floor(timestamp.nanos / 1000 / 1000);
+            return new Date(millis).toISOString() as unknown as { [k: string]: any };
+        }
+        return this.toObject(message, options);
+    }
+};
+
+/** Besides the binary format, the request and response bodies can use JSON or the Protobuf text format.
+ * The request body is decoded according to its Content-Type. The successful response body is encoded according to the
+ * Accept header of the request or, if absent, according to the Content-Type of the request.
+ * As protobufjs does not support the Protobuf text format, a minimal implementation for the test messages is used.
+ */
+const wireFormatContentTypes: { [format in string]: string } = { json: 'application/json', text: 'application/x-protobuf-text' };
+
+function wireFormatOf(contentType: string | undefined): string | undefined {
+    return Object.keys(wireFormatContentTypes).find(format => contentType?.startsWith(wireFormatContentTypes[format]));
+}
+
+function decodeWireFormat(type: protobuf.Type, body: Buffer, wireFormat: string): protobuf.Message {
+    if (wireFormat === 'json') {
+        return type.fromObject(JSON.parse(body.toString()));
+    }
+    const tokens = body.toString().match(/"(?:[^"\\]|\\.)*"|[{}\[\]:;,<>]|[^\s{}\[\]:;,<>"]+/g) ?? [];
+    return type.fromObject(parseTextMessage(type, tokens, { pos: 0 }, undefined));
+}
+
+function parseTextMessage(type: protobuf.Type, tokens: string[], cursor: { pos: number }, end: string | undefined): { [k: string]: any } {
+    const object: { [k: string]: any } = {};
+    while (cursor.pos < tokens.length && tokens[cursor.pos] !== end) {
+        const field = type.fields[tokens[cursor.pos++]];
+        if (tokens[cursor.pos] === ':') {
+            cursor.pos++;
+        }
+        const values: any[] = [];
+        const isList = tokens[cursor.pos] === '[';
+        if (isList) {
+            cursor.pos++;
+        }
+        do {
+            const token = tokens[cursor.pos++];
+            if (token === '{' || token === '<') {
+                values.push(parseTextMessage(field.resolvedType as protobuf.Type, tokens, cursor, token === '{' ? '}' : '>'));
+                cursor.pos++;
+            } else if (token.startsWith('"')) {
+                values.push(JSON.parse(token));
+            } else if (token === 'true' || token === 'false') {
+                values.push(token === 'true');
+            } else {
+                values.push(token); // numbers and enum values are converted by fromObject
+            }
+        } while (isList && tokens[cursor.pos++] === ',');
+        if (field.repeated) {
+            object[field.name] = [...(object[field.name] ?? []), ...values];
+        } else {
+            object[field.name] = values[0];
+        }
+        if (tokens[cursor.pos] === ';' || tokens[cursor.pos] === ',') {
+            cursor.pos++;
+        }
+    }
+    return object;
+}
+
+function encodeWireFormat(type: protobuf.Type, message: protobuf.Message, wireFormat: string): string {
+    if (wireFormat === 'json') {
+        return JSON.stringify(type.toObject(message, { longs: String, enums: String, bytes: String, json: true }));
+    }
+    return formatTextMessage(type, message, '');
+}
+
+function formatTextMessage(type: protobuf.Type, message: protobuf.Message, indent: string): string {
+    const values = message as unknown as { [k: string]: any };
+    return type.fieldsArray
+        .filter(field => Object.prototype.hasOwnProperty.call(values, field.name) && values[field.name] !== null)
+        .flatMap(field => (field.repeated ? values[field.name] : [values[field.name]]).map((value: any) => {
+            if (field.resolvedType instanceof protobuf.Type) {
+                return indent + field.name + ' {\n' + formatTextMessage(field.resolvedType, value, indent + '  ') + indent + '}\n';
+            } else if (value instanceof Uint8Array) {
+                return indent + field.name + ': "' + Array.from(value, byte => '\\x' + byte.toString(16).padStart(2, '0')).join('') + '"\n';
+            } else if (typeof value === 'string') {
+                return indent + field.name + ': ' + JSON.stringify(value) + '\n';
+            }
+            return indent + field.name + ': ' + value.toString() + '\n';
+        }))
+        .join('');
 }
 
 function runHttpServer(handlers: PathHandler[]) {
@@ -118,9 +430,22 @@ function runHttpServer(handlers: PathHandler[]) {
         console.log('=========== ' + req.method + ' ' + req.url);
         console.log(req.rawHeaders.map(s => '  ' + s));
 
+        const url = new URL(req.url ?? "", `http://${req.headers.host}`);
+
+        if (url.pathname === '/happy-day/stream') {
+            const verifyHandler = handlers.find(handler => handler.path === '/happy-day/verify')!;
+            let buffers: any[] = [];
+            req.on('data', chunk => buffers.push(chunk));
+            req.on('end', () => streamHappyDays(verifyHandler, Buffer.concat(buffers), url, res).catch(err => {
+                console.error('Error during streaming: ');
+                console.error(err);
+                res.destroy();
+            }));
+            return;
+        }
+
         const currentHandler = handlers.find(handler =>
-            handler.method.includes(req.method as any) &&
-            new URL(req.url ?? "", `http://${req.headers.host}`).pathname == handler.path
+            handler.method.includes(req.method as any) && url.pathname == handler.path
         );
 
         if (currentHandler === undefined) {
@@ -135,28 +460,67 @@ function runHttpServer(handlers: PathHandler[]) {
         });
 
         req.on('end', async () => {
-            const data = Buffer.concat(buffers);
+            const bodyEncoding = url.searchParams.get('bodyEncoding');
+            const data = decodeBody(Buffer.concat(buffers), bodyEncoding);
+            const requestWireFormat = wireFormatOf(req.headers['content-type']);
+            const responseWireFormat = wireFormatOf(req.headers['accept']) ?? requestWireFormat;
             console.log('Extracted body: Base64(' + data.toString('base64') + '), Binary(' + data + ')');
 
-            const result = new Promise<protobuf.Message>((resolve, reject) => {
+            const result = new Promise<protobuf.Message[]>((resolve, reject) => {
                 try {
-                    const decodedMsg = currentHandler.reqType.decode(data);
-                    console.log('Decoded request: ' + JSON.stringify(decodedMsg, null, 2));
-                    resolve(decodedMsg);
+                    const decodedMsgs: protobuf.Message[] = [];
+                    if (currentHandler.delimited) {
+                        const reader = protobuf.Reader.create(data);
+                        while (reader.pos < reader.len) {
+                            decodedMsgs.push(currentHandler.reqType.decodeDelimited(reader));
+                        }
+                    } else if (requestWireFormat !== undefined) {
+                        decodedMsgs.push(decodeWireFormat(currentHandler.reqType, data, requestWireFormat));
+                    } else {
+                        decodedMsgs.push(currentHandler.reqType.decode(data));
+                    }
+                    decodedMsgs.forEach(decodedMsg => console.log('Decoded request: ' + JSON.stringify(decodedMsg, null, 2)));
+                    resolve(decodedMsgs);
                 } catch (err) {
                     reject(err);
                 }
             })
-                .then(decodedMsg => currentHandler.handler(decodedMsg))
-                .then(([responseType, respMessage]) => {
-                    console.log('Encoding response: ' + JSON.stringify(respMessage, null, 2));
-                    const encodedMsg = responseType.encode(respMessage).finish();
+                .then(decodedMsgs => Promise.all(decodedMsgs.map(decodedMsg => currentHandler.handler(decodedMsg, url))))
+                .then(responses => {
+                    if (!currentHandler.delimited && responseWireFormat !== undefined) {
+                        const [responseType, respMessage] = responses[0];
+                        console.log('Encoding response as ' + responseWireFormat + ': ' + JSON.stringify(respMessage, null, 2));
+                        res.statusCode = 200;
+                        res.setHeader('Content-Type', wireFormatContentTypes[responseWireFormat]);
+                        res.end(encodeBody(Buffer.from(encodeWireFormat(responseType, responseType.fromObject(respMessage), responseWireFormat)), bodyEncoding));
+                        console.log('=========== 200 OK');
+                        return;
+                    }
+                    const writer = protobuf.Writer.create();
+                    for (const [responseType, respMessage] of responses) {
+                        console.log('Encoding response: ' + JSON.stringify(respMessage, null, 2));
+                        if (currentHandler.delimited) {
+                            responseType.encodeDelimited(respMessage, writer);
+                        } else {
+                            responseType.encode(respMessage, writer);
+                        }
+                    }
+                    const encodedMsg = writer.finish();
                     res.statusCode = 200;
                     res.setHeader('Content-Type', 'application/x-protobuf');
-                    res.end(encodedMsg);
+                    res.end(encodeBody(encodedMsg, bodyEncoding));
                     console.log('=========== 200 OK');
                 })
                 .catch(err => {
+                    if (err instanceof HttpStatusError) {
+                        console.log('=========== ' + err.statusCode);
+                        res.statusCode = err.statusCode;
+                        if (err.body !== undefined) {
+                            res.setHeader('Content-Type', err.contentType);
+                        }
+                        res.end(err.body);
+                        return;
+                    }
                     console.error('Error during request handling: ');
                     console.error(err);
                     res.statusCode = 500;
@@ -169,18 +533,113 @@ function runHttpServer(handlers: PathHandler[]) {
 
     http.createServer(requestListener).listen(PORT);
 
+    /* The same paths are served via TLS with the certificates of test/certs. The server certificate is only valid for localhost.
+     * The mutual TLS server additionally only accepts clients with a certificate signed by the test CA.
+     */
+    const tlsOptions: https.ServerOptions = {
+        key: fs.readFileSync(certsDirPath + '/server-key.pem'),
+        cert: fs.readFileSync(certsDirPath + '/server.pem'),
+    };
+    https.createServer(tlsOptions, requestListener).listen(HTTPS_PORT);
+    https.createServer({
+        ...tlsOptions,
+        ca: fs.readFileSync(certsDirPath + '/ca.pem'),
+        requestCert: true,
+        rejectUnauthorized: true,
+    }, requestListener).listen(MTLS_PORT);
+
+    console.log('Listening to TLS ports: ' + HTTPS_PORT + ', ' + MTLS_PORT + ' (mutual TLS)');
+
     // This line is used in the test runner to detect readiness during testing.
     console.log('Listening to port: ' + PORT);
 }
 
+/**
+ * A minimal gRPC server (HTTP/2 without TLS) which does not need any additional dependencies.
+ *
+ * <p>The unary method `happyday.HappyDayService/Verify` reuses the handler of `/happy-day/verify`.
+ * The method `happyday.HappyDayService/Fail` always fails with the status INVALID_ARGUMENT.
+ * Request and response bodies consist of a single message with the gRPC 5-byte length-prefix and
+ * the outcome is sent via the grpc-status and grpc-message trailers.
+ */
+function runGrpcServer(handlers: PathHandler[]) {
+    const grpcStatusOk = 0;
+    const grpcStatusInvalidArgument = 3;
+    const grpcStatusUnimplemented = 12;
+
+    const verifyHandler = handlers.find(handler => handler.path === '/happy-day/verify')!;
+
+    const server = http2.createServer();
+
+    server.on('stream', (stream, headers) => {
+        const path = headers[':path'];
+        console.log('=========== gRPC ' + path);
+
+        const respond = (status: number, message: string, body?: Uint8Array) => {
+            stream.respond({':status': 200, 'content-type': 'application/grpc'}, {waitForTrailers: true});
+            stream.on('wantTrailers', () => {
+                stream.sendTrailers({'grpc-status': status.toString(), 'grpc-message': encodeURIComponent(message)});
+            });
+            if (body === undefined) {
+                stream.end();
+            } else {
+                const prefix = Buffer.alloc(5);
+                prefix.writeUInt32BE(body.length, 1);
+                stream.end(Buffer.concat([prefix, body]));
+            }
+            console.log('=========== gRPC status ' + status);
+        };
+
+        let buffers: any[] = [];
+        stream.on('data', chunk => {
+            buffers.push(chunk);
+        });
+
+        stream.on('end', async () => {
+            const data = Buffer.concat(buffers);
+            console.log('Extracted body: Base64(' + data.toString('base64') + ')');
+
+            try {
+                switch (path) {
+                    case '/happyday.HappyDayService/Verify': {
+                        const length = data.readUInt32BE(1);
+                        const decodedMsg = verifyHandler.reqType.decode(data.subarray(5, 5 + length));
+                        console.log('Decoded request: ' + JSON.stringify(decodedMsg, null, 2));
+                        const [responseType, respMessage] = await verifyHandler.handler(decodedMsg, new URL('http://localhost' + path));
+                        respond(grpcStatusOk, '', responseType.encode(respMessage).finish());
+                        break;
+                    }
+                    case '/happyday.HappyDayService/Fail':
+                        respond(grpcStatusInvalidArgument, 'This method always fails.');
+                        break;
+                    default:
+                        respond(grpcStatusUnimplemented, 'Unknown method ' + path);
+                }
+            } catch (err) {
+                console.error('Error during gRPC request handling: ');
+                console.error(err);
+                respond(grpcStatusInvalidArgument, 'Could not handle request: ' + err);
+            }
+        });
+    });
+
+    server.listen(GRPC_PORT);
+
+    console.log('Listening to gRPC port: ' + GRPC_PORT);
+
+    return handlers;
+}
+
 protobuf.load(protoFilePath)
     .then(root => {
         ProtobufDefs = root;
         HappyDayRequestType = ProtobufDefs.lookupType(protoRequestPath);
         HappyDayResponseType = ProtobufDefs.lookupType(protoResponsePath);
+        MiscInfoType = ProtobufDefs.lookupType('happyday.MiscInfo');
         return undefined;
     })
     .then(defineHandlers)
+    .then(handlers => runGrpcServer(handlers))
     .then(handlers => runHttpServer(handlers));
 
 //
\ No newline at end of file
@@ -11,7 +11,7 @@ fi
 
 WORKING_DIR="$1"
 
-export RUN_CLIENT="docker run --rm -v $WORKING_DIR/test/proto:/proto -v $WORKING_DIR/test/payloads:/payloads --network host"
+export RUN_CLIENT="docker run --rm -v $WORKING_DIR/test/proto:/proto -v $WORKING_DIR/test/payloads:/payloads -v $WORKING_DIR/test/certs:/certs --network host"
 
 export SHOW_LOGS="docker logs"
 